@@ -1,6 +1,8 @@
 package function
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -173,4 +175,117 @@ func TestCalc(t *testing.T) {
 	if sum != 15 || sub != 5 {
 		t.Errorf("Calc(10, 5) = %d, %d; 期望 15, 5", sum, sub)
 	}
-}
\ No newline at end of file
+}
+
+// TestResultPipeline 测试用 Result 串联可能失败的步骤
+func TestResultPipeline(t *testing.T) {
+	r := AndThen(Try(safeDivide(100, 5)), func(n int) (int, error) { return divid2(n, 2) })
+	if n, err := r.Get(); err != nil || n != 10 {
+		t.Errorf("流水线结果 = %d, %v; 期望 10", n, err)
+	}
+
+	// 中间步骤失败后，后续步骤不再执行
+	called := false
+	failed := Map(AndThen(Try(safeDivide(1, 0)), func(n int) (string, error) {
+		called = true
+		return readFile(fmt.Sprint(n))
+	}), func(s string) int { return len(s) })
+	if called || failed.IsOk() {
+		t.Errorf("失败后不应继续执行, called = %t, 结果 = %v", called, failed)
+	}
+	if failed.UnwrapOr(-1) != -1 {
+		t.Errorf("UnwrapOr = %d; 期望 -1", failed.UnwrapOr(-1))
+	}
+
+	recovered := failed.OrElse(func(err error) Result[int] { return Ok(0) })
+	if recovered.Unwrap() != 0 {
+		t.Errorf("OrElse 恢复后 = %v; 期望 Ok(0)", recovered)
+	}
+}
+
+// pathError 用于测试 errors.As 的自定义错误类型
+type pathError struct{ path string }
+
+func (e *pathError) Error() string { return "无效路径: " + e.path }
+
+// TestResultErrors 测试 errors.Is / errors.As 透传
+func TestResultErrors(t *testing.T) {
+	r := Err[int](errorNoFound).MapErr(func(err error) error { return fmt.Errorf("查询用户: %w", err) })
+	if !r.Is(errorNoFound) || !errors.Is(r.Err(), errorNoFound) {
+		t.Errorf("Is(errorNoFound) 期望 true, 错误为 %v", r.Err())
+	}
+	if Ok(1).Is(errorNoFound) {
+		t.Error("成功的结果 Is 期望 false")
+	}
+
+	var target *pathError
+	wrapped := Err[string](fmt.Errorf("打开: %w", &pathError{path: "/tmp/x"}))
+	if !wrapped.As(&target) || target.path != "/tmp/x" {
+		t.Errorf("As 期望得到 pathError, 实际 %v", target)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("对失败的 Result 调用 Unwrap 期望 panic")
+		}
+	}()
+	r.Unwrap()
+}
+
+// TestOption 测试 Option 及其与 Result 的互转
+func TestOption(t *testing.T) {
+	users := map[int]string{1: "tom"}
+	lookup := func(id int) (string, bool) { name, ok := users[id]; return name, ok }
+
+	name := AndThenOption(Some(1), lookup)
+	if v, ok := name.Get(); !ok || v != "tom" {
+		t.Errorf("AndThenOption = %v; 期望 Some(tom)", name)
+	}
+	missing := AndThenOption(Some(2), lookup)
+	if missing.IsSome() || missing.UnwrapOr("guest") != "guest" {
+		t.Errorf("AndThenOption(2) = %v; 期望 None", missing)
+	}
+	if got := MapOption(name, func(s string) int { return len(s) }); got.String() != "Some(3)" {
+		t.Errorf("MapOption = %v; 期望 Some(3)", got)
+	}
+	if got := missing.OrElse(func() Option[string] { return Some("amy") }); got.UnwrapOr("") != "amy" {
+		t.Errorf("OrElse = %v; 期望 Some(amy)", got)
+	}
+
+	if !missing.OkOr(errorNoFound).Is(errorNoFound) {
+		t.Error("None.OkOr 期望返回携带该错误的 Result")
+	}
+	if Err[int](errorNoFound).Option().IsSome() || Ok(5).Option().UnwrapOr(0) != 5 {
+		t.Error("Result.Option 转换结果不正确")
+	}
+	if FromPtr[int](nil).IsSome() {
+		t.Error("FromPtr(nil) 期望 None")
+	}
+}
+
+// TestResultOptionJSON 测试 JSON 编解码
+func TestResultOptionJSON(t *testing.T) {
+	type profile struct {
+		Nickname Option[string] `json:"nickname"`
+		Age      Result[int]    `json:"age"`
+	}
+	in := profile{Nickname: Some("gopher"), Age: Err[int](errorNoFound)}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"nickname":"gopher","age":{"error":"资源未找到"}}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	var out profile
+	if err := json.Unmarshal([]byte(`{"nickname":null,"age":{"value":18}}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Nickname.IsSome() || out.Age.Unwrap() != 18 {
+		t.Errorf("Unmarshal = %v, %v; 期望 None, Ok(18)", out.Nickname, out.Age)
+	}
+	if err := json.Unmarshal(data, &out); err != nil || out.Age.Err().Error() != "资源未找到" {
+		t.Errorf("Unmarshal 错误结果 = %v, %v", out.Age, err)
+	}
+}
@@ -0,0 +1,269 @@
+package function
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+/*
+Result 和 Option：把 (T, error) 和 "可能没有值" 封装成值，方便把多个可能失败的步骤串成流水线。
+
+	r := AndThen(Try(safeDivide(100, 5)), func(n int) (int, error) { return divid2(n, 2) })
+	n, err := r.Get() // 回到 Go 惯用的 (T, error)
+
+Go 的方法不能带类型参数，所以会改变类型的 Map / AndThen 是普通函数，不改变类型的 OrElse 等是方法。
+*/
+
+// ==================== Result ====================
+
+// Result 一个值或一个错误，零值是值为零值的成功结果
+type Result[T any] struct {
+	value T
+	err   error
+}
+
+// Ok 创建成功的结果
+func Ok[T any](value T) Result[T] {
+	return Result[T]{value: value}
+}
+
+// Err 创建失败的结果，err 为 nil 时得到值为零值的成功结果
+func Err[T any](err error) Result[T] {
+	return Result[T]{err: err}
+}
+
+// Try 把 (T, error) 转换为 Result，可以直接包住函数调用：Try(safeDivide(10, 2))
+func Try[T any](value T, err error) Result[T] {
+	if err != nil {
+		return Err[T](err)
+	}
+	return Ok(value)
+}
+
+// Get 转换回 (T, error)，失败时值为零值
+func (r Result[T]) Get() (T, error) {
+	if r.err != nil {
+		var zero T
+		return zero, r.err
+	}
+	return r.value, nil
+}
+
+func (r Result[T]) IsOk() bool  { return r.err == nil }
+func (r Result[T]) IsErr() bool { return r.err != nil }
+
+// Err 返回错误，成功时为 nil
+func (r Result[T]) Err() error {
+	return r.err
+}
+
+// Unwrap 返回值，失败时 panic
+func (r Result[T]) Unwrap() T {
+	if r.err != nil {
+		panic(fmt.Sprintf("function: 对失败的 Result 调用 Unwrap: %v", r.err))
+	}
+	return r.value
+}
+
+// UnwrapOr 返回值，失败时返回 fallback
+func (r Result[T]) UnwrapOr(fallback T) T {
+	if r.err != nil {
+		return fallback
+	}
+	return r.value
+}
+
+// OrElse 失败时调用 f 尝试恢复，成功时原样返回
+func (r Result[T]) OrElse(f func(error) Result[T]) Result[T] {
+	if r.err != nil {
+		return f(r.err)
+	}
+	return r
+}
+
+// MapErr 失败时用 f 转换错误，例如用 fmt.Errorf("...: %w", err) 补充上下文
+func (r Result[T]) MapErr(f func(error) error) Result[T] {
+	if r.err != nil {
+		return Err[T](f(r.err))
+	}
+	return r
+}
+
+// Is 等价于 errors.Is(r.Err(), target)，成功的结果总是返回 false
+func (r Result[T]) Is(target error) bool {
+	return r.err != nil && errors.Is(r.err, target)
+}
+
+// As 等价于 errors.As(r.Err(), target)，成功的结果总是返回 false
+func (r Result[T]) As(target any) bool {
+	return r.err != nil && errors.As(r.err, target)
+}
+
+// Option 成功时返回 Some(值)，失败时返回 None，丢弃错误
+func (r Result[T]) Option() Option[T] {
+	if r.err != nil {
+		return None[T]()
+	}
+	return Some(r.value)
+}
+
+func (r Result[T]) String() string {
+	if r.err != nil {
+		return fmt.Sprintf("Err(%v)", r.err)
+	}
+	return fmt.Sprintf("Ok(%v)", r.value)
+}
+
+// resultJSON Result 的 JSON 形式：成功时为 {"value": ...}，失败时为 {"error": "..."}
+type resultJSON[T any] struct {
+	Value *T      `json:"value,omitempty"`
+	Error *string `json:"error,omitempty"`
+}
+
+func (r Result[T]) MarshalJSON() ([]byte, error) {
+	if r.err != nil {
+		msg := r.err.Error()
+		return json.Marshal(resultJSON[T]{Error: &msg})
+	}
+	return json.Marshal(resultJSON[T]{Value: &r.value})
+}
+
+// UnmarshalJSON 解码 MarshalJSON 的输出。错误只能还原出消息文本，errors.Is 无法再匹配原来的哨兵错误
+func (r *Result[T]) UnmarshalJSON(data []byte) error {
+	var decoded resultJSON[T]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	switch {
+	case decoded.Error != nil:
+		*r = Err[T](errors.New(*decoded.Error))
+	case decoded.Value != nil:
+		*r = Ok(*decoded.Value)
+	default:
+		*r = Ok(*new(T))
+	}
+	return nil
+}
+
+// Map 成功时用 f 转换值，失败时原样传递错误
+func Map[T, U any](r Result[T], f func(T) U) Result[U] {
+	if r.err != nil {
+		return Err[U](r.err)
+	}
+	return Ok(f(r.value))
+}
+
+// AndThen 成功时继续执行下一个可能失败的步骤，f 的签名与 safeDivide、readFile 等函数一致
+func AndThen[T, U any](r Result[T], f func(T) (U, error)) Result[U] {
+	if r.err != nil {
+		return Err[U](r.err)
+	}
+	return Try(f(r.value))
+}
+
+// ==================== Option ====================
+
+// Option 可能有值也可能没有值，零值为 None
+type Option[T any] struct {
+	value T
+	ok    bool
+}
+
+// Some 创建有值的 Option
+func Some[T any](value T) Option[T] {
+	return Option[T]{value: value, ok: true}
+}
+
+// None 创建没有值的 Option
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+// FromPtr 指针为 nil 时返回 None，否则返回指针指向的值
+func FromPtr[T any](p *T) Option[T] {
+	if p == nil {
+		return None[T]()
+	}
+	return Some(*p)
+}
+
+// Get 转换回 Go 惯用的 (值, 是否存在)
+func (o Option[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+func (o Option[T]) IsSome() bool { return o.ok }
+func (o Option[T]) IsNone() bool { return !o.ok }
+
+// UnwrapOr 返回值，没有值时返回 fallback
+func (o Option[T]) UnwrapOr(fallback T) T {
+	if !o.ok {
+		return fallback
+	}
+	return o.value
+}
+
+// OrElse 没有值时调用 f 获取替代值
+func (o Option[T]) OrElse(f func() Option[T]) Option[T] {
+	if !o.ok {
+		return f()
+	}
+	return o
+}
+
+// OkOr 有值时返回成功的 Result，否则返回以 err 失败的 Result
+func (o Option[T]) OkOr(err error) Result[T] {
+	if !o.ok {
+		return Err[T](err)
+	}
+	return Ok(o.value)
+}
+
+func (o Option[T]) String() string {
+	if !o.ok {
+		return "None"
+	}
+	return fmt.Sprintf("Some(%v)", o.value)
+}
+
+// MarshalJSON None 编码为 null，Some 编码为值本身
+func (o Option[T]) MarshalJSON() ([]byte, error) {
+	if !o.ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+func (o *Option[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*o = None[T]()
+		return nil
+	}
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*o = Some(value)
+	return nil
+}
+
+// MapOption 有值时用 f 转换值
+func MapOption[T, U any](o Option[T], f func(T) U) Option[U] {
+	if !o.ok {
+		return None[U]()
+	}
+	return Some(f(o.value))
+}
+
+// AndThenOption 有值时继续执行下一个可能没有结果的步骤，f 的签名与 map 查找的 (值, ok) 一致
+func AndThenOption[T, U any](o Option[T], f func(T) (U, bool)) Option[U] {
+	if !o.ok {
+		return None[U]()
+	}
+	value, ok := f(o.value)
+	if !ok {
+		return None[U]()
+	}
+	return Some(value)
+}
@@ -0,0 +1,65 @@
+package generics
+
+import (
+	"slices"
+	"time"
+)
+
+// BatchHandle 批处理函数，一次接收多个元素。返回错误或 panic 时整批按重试策略重试，
+// 重试耗尽后批中的每个元素都会投递到死信回调。
+type BatchHandle[T any] func(items []T) error
+
+// StartBatch 以批处理模式启动处理器：消费者把队列中的元素攒成批次，
+// 批次达到 maxBatch 个元素，或距离批次中第一个元素被取出已经过了 interval 时调用 handle。
+// Stop 时已经攒起来的批次和队列中剩余的元素都会交给 handle（见 Stop）；Shutdown 会把队列中的元素全部处理完。
+func (h *Handler[T]) StartBatch(size, maxBatch int, interval time.Duration, handle BatchHandle[T]) {
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+	h.start(size, &runner[T]{batch: handle, maxBatch: maxBatch, interval: interval})
+}
+
+// collect 以 first 开头收集一个批次，直到达到 maxBatch、超过 interval 或协程需要退出
+func (h *Handler[T]) collect(first entry[T], r *runner[T], quit <-chan struct{}) []entry[T] {
+	batch := make([]entry[T], 1, r.maxBatch)
+	batch[0] = first
+	deadline := time.Now().Add(r.interval)
+	for len(batch) < r.maxBatch {
+		e, ok := h.next(quit, deadline)
+		if !ok {
+			break
+		}
+		batch = append(batch, e)
+	}
+	return batch
+}
+
+// flush 把 Stop 时队列中剩余的元素按 maxBatch 分批处理
+func (h *Handler[T]) flush(r *runner[T], rest []entry[T]) {
+	for batch := range slices.Chunk(rest, r.maxBatch) {
+		start := time.Now()
+		ok := r.processBatch(batch)
+		h.metrics.observe(time.Since(start), len(batch), ok)
+	}
+}
+
+// processBatch 处理一个批次，返回最终是否成功
+func (r *runner[T]) processBatch(batch []entry[T]) bool {
+	items := make([]T, len(batch))
+	for i, e := range batch {
+		items[i] = e.item
+	}
+	defer func() {
+		for _, e := range batch {
+			r.ack(e)
+		}
+	}()
+	attempts, err := r.attempt(func() error { return safeCall(r.batch, items) })
+	if err != nil {
+		for _, item := range items {
+			r.dead(item, err, attempts)
+		}
+		return false
+	}
+	return true
+}
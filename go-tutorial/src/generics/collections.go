@@ -0,0 +1,449 @@
+package generics
+
+import (
+	"iter"
+)
+
+// 实现 Container[T] 接口的泛型集合。Set 基于 map，元素必须是 comparable；
+// Deque、Queue、List 可以存放任意类型，Remove/Contains 默认用 == 比较元素，
+// 元素不可比较（切片、map、函数等）时需要用 NewXxxFunc 提供比较函数。
+var (
+	_ Container[int] = (*Set[int])(nil)
+	_ Container[int] = (*Queue[int])(nil)
+	_ Container[int] = (*Deque[int])(nil)
+	_ Container[int] = (*List[int])(nil)
+)
+
+// ==================== Set ====================
+
+// Set 基于 map 的哈希集合，零值可以直接使用。只读方法和集合运算把 nil *Set 当作空集合
+type Set[T comparable] struct {
+	items map[T]struct{}
+}
+
+func NewSet[T comparable](items ...T) *Set[T] {
+	s := &Set[T]{items: make(map[T]struct{}, len(items))}
+	for _, item := range items {
+		s.Add(item)
+	}
+	return s
+}
+
+func (s *Set[T]) Add(item T) {
+	if s.items == nil {
+		s.items = make(map[T]struct{})
+	}
+	s.items[item] = struct{}{}
+}
+
+func (s *Set[T]) Remove(item T) bool {
+	if _, ok := s.items[item]; !ok {
+		return false
+	}
+	delete(s.items, item)
+	return true
+}
+
+func (s *Set[T]) Contains(item T) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.items[item]
+	return ok
+}
+
+func (s *Set[T]) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.items)
+}
+
+// All 遍历集合中的元素，顺序不固定
+func (s *Set[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if s == nil {
+			return
+		}
+		for item := range s.items {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Union 返回包含两个集合所有元素的新集合
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+	result := NewSet[T]()
+	for item := range s.All() {
+		result.Add(item)
+	}
+	for item := range other.All() {
+		result.Add(item)
+	}
+	return result
+}
+
+// Intersection 返回同时属于两个集合的元素组成的新集合
+func (s *Set[T]) Intersection(other *Set[T]) *Set[T] {
+	small, large := s, other
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+	result := NewSet[T]()
+	for item := range small.All() {
+		if large.Contains(item) {
+			result.Add(item)
+		}
+	}
+	return result
+}
+
+// Difference 返回属于 s 但不属于 other 的元素组成的新集合
+func (s *Set[T]) Difference(other *Set[T]) *Set[T] {
+	result := NewSet[T]()
+	for item := range s.All() {
+		if !other.Contains(item) {
+			result.Add(item)
+		}
+	}
+	return result
+}
+
+// equal 用 eq 比较两个元素，eq 为 nil 时使用 ==，此时 T 不可比较会 panic
+func equal[T any](eq func(a, b T) bool, a, b T) bool {
+	if eq != nil {
+		return eq(a, b)
+	}
+	return any(a) == any(b)
+}
+
+// ==================== Deque ====================
+
+// Deque 基于可增长环形缓冲区的双端队列，零值可以直接使用
+type Deque[T any] struct {
+	buf   []T
+	head  int // 第一个元素在 buf 中的位置
+	size  int
+	equal func(a, b T) bool // Remove/Contains 使用的比较函数，nil 表示使用 ==
+}
+
+// NewDequeFunc 创建使用 equal 比较元素的双端队列，用于存放不可比较的元素，例如 NewDequeFunc(slices.Equal[[]int])
+func NewDequeFunc[T any](equal func(a, b T) bool) *Deque[T] {
+	return &Deque[T]{equal: equal}
+}
+
+// grow 缓冲区已满时容量翻倍，并把元素重新排列到缓冲区开头
+func (d *Deque[T]) grow() {
+	if d.size < len(d.buf) {
+		return
+	}
+	buf := make([]T, max(2*len(d.buf), 8))
+	for i := 0; i < d.size; i++ {
+		buf[i] = d.buf[(d.head+i)%len(d.buf)]
+	}
+	d.buf = buf
+	d.head = 0
+}
+
+// index 返回第 i 个元素在 buf 中的位置
+func (d *Deque[T]) index(i int) int {
+	return (d.head + i) % len(d.buf)
+}
+
+func (d *Deque[T]) PushBack(item T) {
+	d.grow()
+	d.buf[d.index(d.size)] = item
+	d.size++
+}
+
+func (d *Deque[T]) PushFront(item T) {
+	d.grow()
+	d.head = (d.head - 1 + len(d.buf)) % len(d.buf)
+	d.buf[d.head] = item
+	d.size++
+}
+
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+	item := d.buf[d.head]
+	d.buf[d.head] = zero // 避免缓冲区继续引用已弹出的元素
+	d.head = d.index(1)
+	d.size--
+	return item, true
+}
+
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+	i := d.index(d.size - 1)
+	item := d.buf[i]
+	d.buf[i] = zero
+	d.size--
+	return item, true
+}
+
+func (d *Deque[T]) Front() (T, bool) {
+	if d.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.buf[d.head], true
+}
+
+func (d *Deque[T]) Back() (T, bool) {
+	if d.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.buf[d.index(d.size-1)], true
+}
+
+// At 返回从队首开始的第 i 个元素，越界时 panic
+func (d *Deque[T]) At(i int) T {
+	if i < 0 || i >= d.size {
+		panic("generics: Deque 下标越界")
+	}
+	return d.buf[d.index(i)]
+}
+
+func (d *Deque[T]) Len() int {
+	return d.size
+}
+
+// Add 从队尾加入元素
+func (d *Deque[T]) Add(item T) {
+	d.PushBack(item)
+}
+
+// Remove 移除第一个等于 item 的元素
+func (d *Deque[T]) Remove(item T) bool {
+	for i := 0; i < d.size; i++ {
+		if !equal(d.equal, d.buf[d.index(i)], item) {
+			continue
+		}
+		// 把后面的元素依次前移一位
+		for j := i; j < d.size-1; j++ {
+			d.buf[d.index(j)] = d.buf[d.index(j+1)]
+		}
+		d.PopBack()
+		return true
+	}
+	return false
+}
+
+func (d *Deque[T]) Contains(item T) bool {
+	for i := 0; i < d.size; i++ {
+		if equal(d.equal, d.buf[d.index(i)], item) {
+			return true
+		}
+	}
+	return false
+}
+
+// All 从队首到队尾遍历元素
+func (d *Deque[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := 0; i < d.size; i++ {
+			if !yield(d.buf[d.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward 从队尾到队首遍历元素
+func (d *Deque[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := d.size - 1; i >= 0; i-- {
+			if !yield(d.buf[d.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// ==================== Queue ====================
+
+// Queue 先进先出队列，基于 Deque 实现，零值可以直接使用
+type Queue[T any] struct {
+	deque Deque[T]
+}
+
+// NewQueueFunc 创建使用 equal 比较元素的队列，用于存放不可比较的元素
+func NewQueueFunc[T any](equal func(a, b T) bool) *Queue[T] {
+	q := &Queue[T]{}
+	q.deque.equal = equal
+	return q
+}
+
+// Enqueue 把元素加入队尾
+func (q *Queue[T]) Enqueue(item T) {
+	q.deque.PushBack(item)
+}
+
+// Dequeue 取出队首元素
+func (q *Queue[T]) Dequeue() (T, bool) {
+	return q.deque.PopFront()
+}
+
+// Peek 返回队首元素但不取出
+func (q *Queue[T]) Peek() (T, bool) {
+	return q.deque.Front()
+}
+
+func (q *Queue[T]) Len() int {
+	return q.deque.Len()
+}
+
+func (q *Queue[T]) Add(item T) {
+	q.Enqueue(item)
+}
+
+func (q *Queue[T]) Remove(item T) bool {
+	return q.deque.Remove(item)
+}
+
+func (q *Queue[T]) Contains(item T) bool {
+	return q.deque.Contains(item)
+}
+
+// All 按出队顺序遍历元素
+func (q *Queue[T]) All() iter.Seq[T] {
+	return q.deque.All()
+}
+
+// ==================== List ====================
+
+// listNode 双向链表的节点
+type listNode[T any] struct {
+	value      T
+	prev, next *listNode[T]
+}
+
+// List 双向链表，零值可以直接使用
+type List[T any] struct {
+	head, tail *listNode[T]
+	size       int
+	equal      func(a, b T) bool // Remove/Contains 使用的比较函数，nil 表示使用 ==
+}
+
+// NewListFunc 创建使用 equal 比较元素的链表，用于存放不可比较的元素
+func NewListFunc[T any](equal func(a, b T) bool) *List[T] {
+	return &List[T]{equal: equal}
+}
+
+func (l *List[T]) PushFront(value T) {
+	node := &listNode[T]{value: value, next: l.head}
+	if l.head != nil {
+		l.head.prev = node
+	} else {
+		l.tail = node
+	}
+	l.head = node
+	l.size++
+}
+
+func (l *List[T]) PushBack(value T) {
+	node := &listNode[T]{value: value, prev: l.tail}
+	if l.tail != nil {
+		l.tail.next = node
+	} else {
+		l.head = node
+	}
+	l.tail = node
+	l.size++
+}
+
+func (l *List[T]) PopFront() (T, bool) {
+	if l.head == nil {
+		var zero T
+		return zero, false
+	}
+	node := l.head
+	l.unlink(node)
+	return node.value, true
+}
+
+func (l *List[T]) PopBack() (T, bool) {
+	if l.tail == nil {
+		var zero T
+		return zero, false
+	}
+	node := l.tail
+	l.unlink(node)
+	return node.value, true
+}
+
+// unlink 把节点从链表中摘除
+func (l *List[T]) unlink(node *listNode[T]) {
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		l.head = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		l.tail = node.prev
+	}
+	node.prev, node.next = nil, nil
+	l.size--
+}
+
+func (l *List[T]) Len() int {
+	return l.size
+}
+
+// Add 在链表尾部加入元素
+func (l *List[T]) Add(value T) {
+	l.PushBack(value)
+}
+
+// Remove 移除第一个等于 value 的元素
+func (l *List[T]) Remove(value T) bool {
+	for node := l.head; node != nil; node = node.next {
+		if equal(l.equal, node.value, value) {
+			l.unlink(node)
+			return true
+		}
+	}
+	return false
+}
+
+func (l *List[T]) Contains(value T) bool {
+	for node := l.head; node != nil; node = node.next {
+		if equal(l.equal, node.value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// All 从头到尾遍历元素
+func (l *List[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for node := l.head; node != nil; node = node.next {
+			if !yield(node.value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward 从尾到头遍历元素
+func (l *List[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for node := l.tail; node != nil; node = node.prev {
+			if !yield(node.value) {
+				return
+			}
+		}
+	}
+}
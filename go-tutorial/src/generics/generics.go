@@ -1,8 +1,12 @@
 package generics
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
 /*
@@ -48,6 +52,147 @@ func (s *Stack[T]) IsEmpty() bool {
 	return len(s.elements) == 0
 }
 
+// Peek 返回栈顶元素但不弹出
+func (s *Stack[T]) Peek() (T, bool) {
+	if len(s.elements) == 0 {
+		var zero T
+		return zero, false
+	}
+	return s.elements[len(s.elements)-1], true
+}
+
+// Len 返回栈中元素的数量
+func (s *Stack[T]) Len() int {
+	return len(s.elements)
+}
+
+// Clear 清空栈
+func (s *Stack[T]) Clear() {
+	clear(s.elements) // 避免底层数组继续引用已清空的元素
+	s.elements = s.elements[:0]
+}
+
+// PushAll 按顺序依次压入多个元素，最后一个元素位于栈顶
+func (s *Stack[T]) PushAll(elements ...T) {
+	s.elements = append(s.elements, elements...)
+}
+
+// All 返回从栈顶到栈底遍历元素的迭代器，遍历过程中不应修改栈
+func (s *Stack[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := len(s.elements) - 1; i >= 0; i-- {
+			if !yield(s.elements[i]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON 把栈编码为从栈底到栈顶排列的 JSON 数组，便于按原顺序还原。
+// 使用值接收者，栈以值的形式出现（例如作为结构体字段）时同样能被编码，而不是变成 {}
+func (s Stack[T]) MarshalJSON() ([]byte, error) {
+	if s.elements == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s.elements)
+}
+
+// UnmarshalJSON 从栈底到栈顶排列的 JSON 数组还原栈
+func (s *Stack[T]) UnmarshalJSON(data []byte) error {
+	var elements []T
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return err
+	}
+	s.elements = elements
+	return nil
+}
+
+// SyncStack 并发安全的栈，方法与 Stack 相同。零值可以直接使用，栈在第一次使用时分配，
+// 之后复制得到的 SyncStack 与原值共享同一个栈，因此可以按值嵌入结构体并随结构体一起按值编码
+type SyncStack[T any] struct {
+	state atomic.Value // *syncStackState[T]，不直接持有锁，复制 SyncStack 不会复制锁
+}
+
+type syncStackState[T any] struct {
+	mu    sync.RWMutex
+	stack Stack[T]
+}
+
+// load 返回栈的状态，还没有分配时分配一个
+func (s *SyncStack[T]) load() *syncStackState[T] {
+	if state, ok := s.state.Load().(*syncStackState[T]); ok {
+		return state
+	}
+	s.state.CompareAndSwap(nil, new(syncStackState[T]))
+	return s.state.Load().(*syncStackState[T])
+}
+
+func (s *SyncStack[T]) Push(element T) {
+	state := s.load()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.stack.Push(element)
+}
+func (s *SyncStack[T]) PushAll(elements ...T) {
+	state := s.load()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.stack.PushAll(elements...)
+}
+func (s *SyncStack[T]) Pop() (T, bool) {
+	state := s.load()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.stack.Pop()
+}
+func (s *SyncStack[T]) Peek() (T, bool) {
+	state := s.load()
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.stack.Peek()
+}
+func (s *SyncStack[T]) Len() int {
+	state := s.load()
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.stack.Len()
+}
+func (s *SyncStack[T]) IsEmpty() bool {
+	return s.Len() == 0
+}
+func (s *SyncStack[T]) Clear() {
+	state := s.load()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.stack.Clear()
+}
+
+// All 遍历调用时刻的快照（从栈顶到栈底），遍历期间不持有锁，可以安全地修改栈
+func (s *SyncStack[T]) All() iter.Seq[T] {
+	state := s.load()
+	state.mu.RLock()
+	snapshot := Stack[T]{elements: slices.Clone(state.stack.elements)}
+	state.mu.RUnlock()
+	return snapshot.All()
+}
+
+// MarshalJSON 与 Stack 一样使用值接收者，栈作为非指针字段时同样能被编码
+func (s SyncStack[T]) MarshalJSON() ([]byte, error) {
+	state, ok := s.state.Load().(*syncStackState[T])
+	if !ok {
+		return []byte("[]"), nil
+	}
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.stack.MarshalJSON()
+}
+func (s *SyncStack[T]) UnmarshalJSON(data []byte) error {
+	state := s.load()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.stack.UnmarshalJSON(data)
+}
+
 // 3.泛型接口
 type Comparable[T any] interface {
 	Compare(other T) int
@@ -96,62 +241,31 @@ type Pair[K, V any] struct {
 	value V
 }
 
+// *Pair 实现了 PairInterface：读取方法使用值接收者，修改方法必须使用指针接收者
+var _ PairInterface[string, int] = (*Pair[string, int])(nil)
+
+func NewPair[K, V any](key K, value V) Pair[K, V] {
+	return Pair[K, V]{key: key, value: value}
+}
+
 func (p Pair[K, V]) Key() K {
 	return p.key
 }
 func (p Pair[K, V]) Value() V {
 	return p.value
 }
+func (p *Pair[K, V]) SetKey(key K) {
+	p.key = key
+}
+func (p *Pair[K, V]) SetValue(value V) {
+	p.value = value
+}
+func (p Pair[K, V]) String() string {
+	return fmt.Sprintf("(%v, %v)", p.key, p.value)
+}
 
 type AdderFunc[T any] func(a, b T) T
 
 func AddTest[T int](a AdderFunc[T]) T {
 	return a(1, 2)
 }
-
-//////////////////////////////////////////////////////////////////
-// Handler实现了一个基于Go泛型的异步队列处理器，它是一个通用的生产者-消费者模式实现，可以处理任意类型的数据项。
-/////////////////////////////////////////////////////////////////
-type Handle[T any] func(item T)
-type Handler[T any] struct {
-	cancel context.CancelFunc
-	itemChan   chan T
-}
-
-func NewHandler[T any]() *Handler[T] {
-	return &Handler[T]{}
-}
-func (h *Handler[T]) Start(size int, handle Handle[T]) {
-	h.Stop()
-	ctx, cancel := context.WithCancel(context.Background())
-	h.itemChan = make(chan T, size)
-	h.cancel = cancel
-	go func() {
-		for {
-			select {
-			case item := <-h.itemChan:
-				handle(item)
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-}
-func (h *Handler[T]) Stop() {
-	if h.cancel != nil {
-		h.cancel()
-		h.cancel = nil
-		h.itemChan = nil
-	}
-}
-func (h *Handler[T]) Push(item T) bool{
-	if h.itemChan == nil {
-		return false
-	}
-	select {
-	case h.itemChan <- item:
-		return true
-	default:
-		return false
-	}
-}
\ No newline at end of file
@@ -1,7 +1,20 @@
 package generics
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
+	"maps"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -15,16 +28,1146 @@ func TestGenerics(t *testing.T) {
 	}
 	handler.Start(10, printstring)
 
-	 for i := 0; i < 15; i++ {
-        success := handler.Push(fmt.Sprintf("项目-%d", i))
-        if !success {
-            fmt.Printf("队列已满，无法推送项目-%d\n", i)
-        }
-        time.Sleep(50 * time.Millisecond)
-    }
+	for i := 0; i < 15; i++ {
+		success := handler.Push(fmt.Sprintf("项目-%d", i))
+		if !success {
+			fmt.Printf("队列已满，无法推送项目-%d\n", i)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
 
 	// 运行一段时间后停止处理器
-    time.Sleep(2 * time.Second)
-	
+	time.Sleep(2 * time.Second)
+
+	handler.Stop()
+}
+
+func TestHandlerWorkers(t *testing.T) {
+	handler := NewHandler[int]()
+	handler.SetWorkers(4)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	handler.Start(10, func(item int) {
+		defer wg.Done()
+		n := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	})
+	defer handler.Stop()
+
+	wg.Add(8)
+	for i := 0; i < 8; i++ {
+		if !handler.Push(i) {
+			t.Fatalf("推送 %d 失败", i)
+		}
+	}
+	wg.Wait()
+	if peak < 2 || peak > 4 {
+		t.Errorf("并发处理数 = %d; 期望 2~4", peak)
+	}
+
+	// 运行期间调整协程数量
+	handler.SetWorkers(1)
+	if handler.Workers() != 1 {
+		t.Errorf("Workers() = %d; 期望 1", handler.Workers())
+	}
+	atomic.StoreInt32(&peak, 0)
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		handler.Push(i)
+	}
+	wg.Wait()
+	if peak != 1 {
+		t.Errorf("缩容后并发处理数 = %d; 期望 1", peak)
+	}
+}
+
+func TestHandlerShutdown(t *testing.T) {
+	handler := NewHandler[int]()
+	var processed int32
+	handler.Start(10, func(item int) {
+		atomic.AddInt32(&processed, 1)
+	})
+	for i := 0; i < 10; i++ {
+		handler.Push(i)
+	}
+
+	rest, err := handler.Shutdown(context.Background())
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("Shutdown() = %v, %v; 期望全部排空", rest, err)
+	}
+	if processed != 10 {
+		t.Errorf("已处理 %d 个; 期望 10", processed)
+	}
+	if handler.Push(100) {
+		t.Error("关闭后 Push 应该返回 false")
+	}
+
+	// ctx 超时时返回尚未处理的元素：第一个元素阻塞住消费者，其余 9 个留在队列中
+	started := make(chan struct{})
+	block := make(chan struct{})
+	defer close(block)
+	handler.Start(10, func(item int) {
+		if item == 0 {
+			close(started)
+		}
+		<-block
+	})
+	for i := 0; i < 10; i++ {
+		handler.Push(i)
+	}
+	<-started
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	rest, err = handler.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() 错误 = %v; 期望 DeadlineExceeded", err)
+	}
+	if fmt.Sprint(rest) != "[1 2 3 4 5 6 7 8 9]" {
+		t.Errorf("返回的未处理元素 = %v; 期望 [1 2 3 4 5 6 7 8 9]", rest)
+	}
+}
+
+func TestHandlerStopWaits(t *testing.T) {
+	handler := NewHandler[int]()
+	var finished int32
+	handler.Start(10, func(item int) {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	})
+	handler.Push(1)
+	time.Sleep(10 * time.Millisecond)
+	handler.Stop()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("Stop 应该等待正在执行的 Handle 返回")
+	}
+}
+
+func TestHandlerCancelFromHandle(t *testing.T) {
+	handler := NewHandler[int]()
+	pushed := make(chan struct{})
+	cancelled := make(chan []int, 1)
+	handler.Start(10, func(item int) {
+		<-pushed
+		cancelled <- handler.Cancel() // Cancel 不等待，在 Handle 中调用不会死锁
+	})
+	handler.Push(0)
+	handler.Push(1)
+	handler.Push(2)
+	close(pushed)
+	select {
+	case rest := <-cancelled:
+		if !slices.Equal(rest, []int{1, 2}) {
+			t.Errorf("Cancel() = %v; 期望返回 [1 2]", rest)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("在 Handle 中调用 Cancel 发生死锁")
+	}
+	if handler.Push(3) {
+		t.Error("Cancel 之后 Push 应该失败")
+	}
+
+	// 取消之后可以重新启动
+	handled := make(chan int, 1)
+	handler.Start(10, func(item int) {
+		handled <- item
+	})
+	handler.Push(4)
+	if item := <-handled; item != 4 {
+		t.Errorf("重新启动后处理了 %d; 期望 4", item)
+	}
 	handler.Stop()
-}
\ No newline at end of file
+}
+
+func TestHandlerOverflowPolicy(t *testing.T) {
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int
+	handle := func(item int) {
+		<-block
+		mu.Lock()
+		handled = append(handled, item)
+		mu.Unlock()
+	}
+
+	// 丢弃最早的元素：第 0 个元素已被消费者取走，队列中 1 被 3 挤掉
+	handler := NewHandler[int]()
+	handler.SetOverflowPolicy(OverflowDropOldest)
+	handler.Start(2, handle)
+	handler.Push(0)
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i <= 3; i++ {
+		if !handler.Push(i) {
+			t.Errorf("DropOldest 策略下 Push(%d) 应该返回 true", i)
+		}
+	}
+	close(block)
+	handler.Shutdown(context.Background())
+	if fmt.Sprint(handled) != "[0 2 3]" {
+		t.Errorf("处理顺序 = %v; 期望 [0 2 3]", handled)
+	}
+
+	// 溢出回调
+	var spilled []int
+	handler = NewHandler[int]()
+	handler.SetOverflowPolicy(OverflowSpill)
+	handler.SetSpill(func(item int) { spilled = append(spilled, item) })
+	handler.Start(1, func(item int) { time.Sleep(50 * time.Millisecond) })
+	handler.Push(0)
+	time.Sleep(10 * time.Millisecond)
+	handler.Push(1)
+	if handler.Push(2) {
+		t.Error("Spill 策略下队列已满时 Push 应该返回 false")
+	}
+	handler.Stop()
+	if fmt.Sprint(spilled) != "[2]" {
+		t.Errorf("溢出元素 = %v; 期望 [2]", spilled)
+	}
+}
+
+func TestHandlerPushContext(t *testing.T) {
+	handler := NewHandler[int]()
+	handler.SetOverflowPolicy(OverflowBlock)
+	var processed int32
+	handler.Start(1, func(item int) {
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&processed, 1)
+	})
+
+	// 阻塞策略下不再需要忙等重试
+	for i := 0; i < 20; i++ {
+		if !handler.Push(i) {
+			t.Fatalf("Block 策略下 Push(%d) 失败", i)
+		}
+	}
+	handler.Shutdown(context.Background())
+	if processed != 20 {
+		t.Errorf("已处理 %d 个; 期望 20", processed)
+	}
+
+	// 队列一直满时 PushContext 随 ctx 超时返回
+	handler.Start(1, func(item int) { time.Sleep(200 * time.Millisecond) })
+	defer handler.Stop()
+	handler.Push(0)
+	time.Sleep(10 * time.Millisecond)
+	handler.Push(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := handler.PushContext(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PushContext() = %v; 期望 DeadlineExceeded", err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second}
+	if got := policy.backoff(3); got != 4*time.Second {
+		t.Errorf("backoff(3) = %v; 期望 4s", got)
+	}
+	// 不限制上限时，指数增长超出 int64 范围后应停在最大值，而不是溢出成负数
+	for _, retry := range []int{40, 64, 2000} {
+		if got := policy.backoff(retry); got != math.MaxInt64 {
+			t.Errorf("backoff(%d) = %v; 期望 %v", retry, got, time.Duration(math.MaxInt64))
+		}
+	}
+	policy.MaxBackoff = time.Minute
+	if got := policy.backoff(2000); got != time.Minute {
+		t.Errorf("backoff(2000) = %v; 期望 MaxBackoff 1m", got)
+	}
+	if got := (RetryPolicy{}).backoff(2000); got != 0 {
+		t.Errorf("零值策略的 backoff(2000) = %v; 期望 0", got)
+	}
+
+	// 超出范围的 Jitter 按边界处理，抖动后的等待时间不会是负数
+	handler := NewHandler[int]()
+	for jitter, want := range map[float64]float64{5: 1, -1: 0, 0.5: 0.5} {
+		handler.SetRetry(RetryPolicy{InitialBackoff: time.Second, Jitter: jitter})
+		if handler.retry.Jitter != want {
+			t.Errorf("SetRetry 后 Jitter = %v; 期望 %v", handler.retry.Jitter, want)
+		}
+		for i := 0; i < 100; i++ {
+			if got := handler.retry.backoff(1); got < 0 || got > 2*time.Second {
+				t.Errorf("Jitter %v 时 backoff(1) = %v; 期望在 0~2s 之间", jitter, got)
+			}
+		}
+	}
+}
+
+func TestHandlerRetryAndDeadLetter(t *testing.T) {
+	errBusy := errors.New("busy")
+	var mu sync.Mutex
+	attempts := map[int]int{}
+	var letters []DeadLetter[int]
+
+	handler := NewHandler[int]()
+	handler.SetRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: 5 * time.Millisecond, Jitter: 0.5})
+	handler.SetDeadLetter(func(letter DeadLetter[int]) {
+		mu.Lock()
+		letters = append(letters, letter)
+		mu.Unlock()
+	})
+	handler.StartE(10, func(item int) error {
+		mu.Lock()
+		attempts[item]++
+		n := attempts[item]
+		mu.Unlock()
+		switch {
+		case item == 1 && n < 3: // 前两次失败，第三次成功
+			return errBusy
+		case item == 2: // 一直失败
+			return errBusy
+		case item == 3:
+			panic("坏数据")
+		}
+		return nil
+	})
+	for i := 0; i < 4; i++ {
+		handler.Push(i)
+	}
+	handler.Shutdown(context.Background())
+
+	if attempts[0] != 1 || attempts[1] != 3 || attempts[2] != 3 || attempts[3] != 3 {
+		t.Errorf("尝试次数 = %v; 期望 map[0:1 1:3 2:3 3:3]", attempts)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("死信数量 = %d; 期望 2", len(letters))
+	}
+	for _, letter := range letters {
+		var panicErr *PanicError
+		switch letter.Item {
+		case 2:
+			if !errors.Is(letter.Err, errBusy) || letter.Attempts != 3 {
+				t.Errorf("死信 %+v; 期望 busy 错误且尝试 3 次", letter)
+			}
+		case 3:
+			if !errors.As(letter.Err, &panicErr) || panicErr.Value != "坏数据" {
+				t.Errorf("死信错误 = %v; 期望 PanicError", letter.Err)
+			}
+		default:
+			t.Errorf("意外的死信 %+v", letter)
+		}
+	}
+}
+
+func TestHandlerRecoverPanic(t *testing.T) {
+	handler := NewHandler[int]()
+	var processed int32
+	handler.Start(10, func(item int) {
+		if item == 0 {
+			panic("第一个元素出错")
+		}
+		atomic.AddInt32(&processed, 1)
+	})
+	for i := 0; i < 3; i++ {
+		handler.Push(i)
+	}
+	handler.Shutdown(context.Background())
+	if processed != 2 {
+		t.Errorf("panic 之后已处理 %d 个; 期望 2", processed)
+	}
+}
+
+func TestHandlerStats(t *testing.T) {
+	handler := NewHandler[int]()
+	handler.SetWorkers(2)
+	release := make(chan struct{})
+	handler.StartE(3, func(item int) error {
+		<-release
+		if item%2 == 1 {
+			return errors.New("奇数失败")
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		if handler.Push(i) {
+			accepted++
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stats := handler.Stats()
+	if stats.Capacity != 3 || stats.Workers != 2 || stats.QueueDepth != 3 {
+		t.Errorf("Stats() = %+v; 期望容量 3、协程 2、队列深度 3", stats)
+	}
+	if stats.Accepted != uint64(accepted) || stats.Rejected != uint64(10-accepted) {
+		t.Errorf("Accepted/Rejected = %d/%d; 期望 %d/%d", stats.Accepted, stats.Rejected, accepted, 10-accepted)
+	}
+
+	close(release)
+	handler.Shutdown(context.Background())
+	stats = handler.Stats()
+	if stats.Processed+stats.Failed != uint64(accepted) || stats.QueueDepth != 0 {
+		t.Errorf("Processed+Failed = %d, 队列深度 %d; 期望 %d, 0", stats.Processed+stats.Failed, stats.QueueDepth, accepted)
+	}
+	if stats.LatencyP50 <= 0 || stats.LatencyP50 > stats.LatencyP99 {
+		t.Errorf("延迟分位数 P50=%v P99=%v 不合理", stats.LatencyP50, stats.LatencyP99)
+	}
+}
+
+func BenchmarkHandlerStats(b *testing.B) {
+	handler := NewHandler[int]()
+	handler.Start(10, func(item int) {})
+	defer handler.Stop()
+	for i := 0; i < latencySamples; i++ {
+		handler.metrics.observe(time.Duration(i), 1, true)
+	}
+	for i := 0; i < b.N; i++ {
+		handler.Stats()
+	}
+}
+
+func TestHandlerBatch(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+	snapshot := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return fmt.Sprint(batches)
+	}
+	handled := make(chan struct{}, 10)
+	handler := NewHandler[int]()
+	handler.StartBatch(100, 4, 30*time.Millisecond, func(items []int) error {
+		mu.Lock()
+		batches = append(batches, items)
+		mu.Unlock()
+		handled <- struct{}{}
+		return nil
+	})
+
+	// 10 个元素：两批满 4 个，剩下 2 个在 interval 到期后发出
+	for i := 0; i < 10; i++ {
+		handler.Push(i)
+	}
+	for i := 0; i < 3; i++ {
+		<-handled
+	}
+	if got := snapshot(); got != "[[0 1 2 3] [4 5 6 7] [8 9]]" {
+		t.Errorf("批次 = %s; 期望 [[0 1 2 3] [4 5 6 7] [8 9]]", got)
+	}
+
+	// 停止时未攒满的批次立即发出，无论元素已经被消费者取走还是仍在队列中
+	handler.Push(10)
+	handler.Stop()
+	if got := snapshot(); got != "[[0 1 2 3] [4 5 6 7] [8 9] [10]]" {
+		t.Errorf("停止后的批次 = %s; 期望最后一批为 [10]", got)
+	}
+	if stats := handler.Stats(); stats.Processed != 11 {
+		t.Errorf("Processed = %d; 期望 11", stats.Processed)
+	}
+
+	// 停止时队列中剩余的元素也按 maxBatch 分批交给 handle，而不是被丢弃。
+	// 第一个批次阻塞到 Stop 取出队列中剩余的元素之后，保证剩余的元素由 Stop 分批
+	mu.Lock()
+	batches = nil
+	mu.Unlock()
+	started := make(chan struct{})
+	gate := make(chan struct{})
+	handler.StartBatch(100, 4, time.Hour, func(items []int) error {
+		if items[0] == 0 {
+			close(started)
+			<-gate
+		}
+		mu.Lock()
+		batches = append(batches, items)
+		mu.Unlock()
+		return nil
+	})
+	handler.mu.Lock()
+	stopped := handler.runner.stopped
+	handler.mu.Unlock()
+	go func() {
+		<-stopped
+		close(gate)
+	}()
+	for i := 0; i < 10; i++ {
+		handler.Push(i)
+	}
+	<-started
+	handler.Stop()
+	if got := snapshot(); got != "[[0 1 2 3] [4 5 6 7] [8 9]]" {
+		t.Errorf("Stop 后的批次 = %s; 期望 [[0 1 2 3] [4 5 6 7] [8 9]]", got)
+	}
+}
+
+func TestPartitionedHandler(t *testing.T) {
+	type event struct {
+		user string
+		seq  int
+	}
+	handler := NewPartitionedHandler(4, func(e event) string { return e.user })
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var running, peak int32
+	handler.Start(100, func(e event) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		mu.Lock()
+		got[e.user] = append(got[e.user], e.seq)
+		mu.Unlock()
+	})
+
+	users := []string{"alice", "bob", "carol", "dave", "erin", "frank"}
+	for seq := 0; seq < 10; seq++ {
+		for _, user := range users {
+			if !handler.Push(event{user, seq}) {
+				t.Fatalf("推送 %s-%d 失败", user, seq)
+			}
+		}
+	}
+	if _, err := handler.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, user := range users {
+		if fmt.Sprint(got[user]) != "[0 1 2 3 4 5 6 7 8 9]" {
+			t.Errorf("%s 的处理顺序 = %v; 期望按顺序处理", user, got[user])
+		}
+	}
+	if peak < 2 {
+		t.Errorf("并发处理数 = %d; 期望不同 key 并行处理", peak)
+	}
+	if stats := handler.Stats(); stats.Processed != 60 || stats.Workers != 4 {
+		t.Errorf("Stats() = %+v; 期望处理 60 个、4 个协程", stats)
+	}
+}
+
+// gatedCodec 编码指定元素时阻塞，模拟缓慢的编码或磁盘同步；开始阻塞时关闭 blocked
+type gatedCodec struct {
+	JSONCodec[string]
+	slow    string
+	blocked chan struct{}
+	gate    chan struct{}
+}
+
+func (c gatedCodec) Encode(item string) ([]byte, error) {
+	if item == c.slow {
+		close(c.blocked)
+		<-c.gate
+	}
+	return c.JSONCodec.Encode(item)
+}
+
+func TestHandlerWALConcurrentPush(t *testing.T) {
+	handler := NewHandler[string]()
+	codec := gatedCodec{slow: "slow", blocked: make(chan struct{}), gate: make(chan struct{})}
+	if err := handler.EnableWAL(filepath.Join(t.TempDir(), "jobs.wal"), codec); err != nil {
+		t.Fatal(err)
+	}
+	defer handler.CloseWAL()
+	handled := make(chan string, 2)
+	handler.Start(10, func(item string) { handled <- item })
+
+	go handler.Push("slow")
+	<-codec.blocked
+	// 写日志期间不持有处理器的锁，其他 Push 和消费者不会被阻塞
+	go handler.Push("fast")
+	select {
+	case item := <-handled:
+		if item != "fast" {
+			t.Errorf("先处理的元素 = %s; 期望 fast", item)
+		}
+	case <-time.After(time.Second):
+		close(codec.gate)
+		t.Fatal("一个 Push 写日志时阻塞了其他 Push 和消费者")
+	}
+	close(codec.gate)
+	if item := <-handled; item != "slow" {
+		t.Errorf("第二个处理的元素 = %s; 期望 slow", item)
+	}
+}
+
+func TestHandlerWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.wal")
+
+	// 第一次运行：处理到第 3 个元素时停止，队列中剩余的元素被丢弃
+	handler := NewHandler[string]()
+	if err := handler.EnableWAL(path, JSONCodec[string]{}); err != nil {
+		t.Fatal(err)
+	}
+	var processed int32
+	block := make(chan struct{})
+	handler.Start(10, func(item string) {
+		if atomic.AddInt32(&processed, 1) > 2 {
+			<-block
+		}
+	})
+	for i := 0; i < 5; i++ {
+		handler.Push(fmt.Sprintf("job-%d", i))
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(block)
+	handler.Stop()
+	handler.CloseWAL()
+
+	// 模拟损坏的文件尾部：一条校验和不对的完整记录，之后是写了一半的记录和无意义的字节
+	corrupt := appendRecord(nil, 100, &walRecord[string]{data: []byte(`"job-9"`)})
+	corrupt[len(corrupt)-5] ^= 0xff
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	file.Write(corrupt)
+	file.Write([]byte{'P', 9})
+	file.Write([]byte("garbage\x00\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"))
+	file.Close()
+
+	// 第二次运行：重放未确认的元素
+	handler = NewHandler[string]()
+	if err := handler.EnableWAL(path, JSONCodec[string]{}); err != nil {
+		t.Fatal(err)
+	}
+	defer handler.CloseWAL()
+	var mu sync.Mutex
+	var replayed []string
+	handler.Start(10, func(item string) {
+		mu.Lock()
+		replayed = append(replayed, item)
+		mu.Unlock()
+	})
+	handler.Push("job-5")
+	handler.Shutdown(context.Background())
+
+	// job-2 在 Stop 等待期间处理完成并被确认，只有被丢弃的元素会重新投递
+	if fmt.Sprint(replayed) != "[job-3 job-4 job-5]" {
+		t.Errorf("重放的元素 = %v; 期望 [job-3 job-4 job-5]", replayed)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("全部确认后日志应该被压缩为空: %v, %v", info.Size(), err)
+	}
+}
+
+func TestHandlerPriorityAndDelay(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	started := make(chan struct{})
+	block := make(chan struct{})
+	handler := NewHandler[string]()
+	handler.Start(5, func(item string) {
+		if item == "first" {
+			close(started)
+		}
+		<-block
+		mu.Lock()
+		order = append(order, item)
+		mu.Unlock()
+	})
+
+	// 第一个元素占住消费者，后面的元素在队列中按优先级和投递时间排序
+	handler.Push("first")
+	<-started
+	handler.Push("low", WithPriority(-1))
+	handler.Push("normal")
+	handler.Push("urgent", WithPriority(10))
+	handler.Push("later", WithPriority(100), WithDelay(50*time.Millisecond))
+	if stats := handler.Stats(); stats.QueueDepth != 4 || stats.Delayed != 1 {
+		t.Errorf("QueueDepth/Delayed = %d/%d; 期望 4/1", stats.QueueDepth, stats.Delayed)
+	}
+	// 延迟元素同样占用容量
+	handler.Push("fill")
+	if handler.Push("overflow") {
+		t.Error("队列已满时 Push 应该返回 false")
+	}
+
+	start := time.Now()
+	close(block)
+	handler.Shutdown(context.Background())
+	if fmt.Sprint(order) != "[first urgent normal fill low later]" {
+		t.Errorf("处理顺序 = %v; 期望 [first urgent normal fill low later]", order)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("延迟元素在 %v 后就被处理; 期望等到投递时间", elapsed)
+	}
+}
+
+func TestStack(t *testing.T) {
+	var stack Stack[int]
+	stack.PushAll(1, 2, 3)
+	stack.Push(4)
+	if top, ok := stack.Peek(); !ok || top != 4 || stack.Len() != 4 {
+		t.Errorf("Peek() = %d, %t, Len() = %d; 期望 4, true, 4", top, ok, stack.Len())
+	}
+	if got := slices.Collect(stack.All()); fmt.Sprint(got) != "[4 3 2 1]" {
+		t.Errorf("All() = %v; 期望从栈顶到栈底 [4 3 2 1]", got)
+	}
+
+	data, err := json.Marshal(&stack)
+	if err != nil || string(data) != "[1,2,3,4]" {
+		t.Errorf("json.Marshal = %s, %v; 期望 [1,2,3,4]", data, err)
+	}
+	var restored Stack[int]
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if top, _ := restored.Pop(); top != 4 || restored.Len() != 3 {
+		t.Errorf("还原后 Pop() = %d, Len() = %d; 期望 4, 3", top, restored.Len())
+	}
+
+	// 栈作为非指针字段、外层结构体按值编码时也不能丢失数据
+	type history struct {
+		Undo Stack[string]
+	}
+	var h history
+	h.Undo.PushAll("a", "b")
+	data, err = json.Marshal(h)
+	if err != nil || string(data) != `{"Undo":["a","b"]}` {
+		t.Errorf("json.Marshal(结构体) = %s, %v; 期望 {\"Undo\":[\"a\",\"b\"]}", data, err)
+	}
+	var decoded history
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Undo.Len() != 2 {
+		t.Errorf("json.Unmarshal(结构体) 得到 %d 个元素, %v; 期望 2", decoded.Undo.Len(), err)
+	}
+
+	stack.Clear()
+	if !stack.IsEmpty() {
+		t.Error("Clear 之后栈应该为空")
+	}
+	if _, ok := stack.Peek(); ok {
+		t.Error("空栈 Peek 应该返回 false")
+	}
+}
+
+func TestSyncStack(t *testing.T) {
+	var stack SyncStack[int]
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				stack.Push(j)
+				for range stack.All() {
+					stack.Peek() // 遍历快照时可以继续访问栈
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if stack.Len() != 1000 {
+		t.Errorf("Len() = %d; 期望 1000", stack.Len())
+	}
+	popped := 0
+	for !stack.IsEmpty() {
+		stack.Pop()
+		popped++
+	}
+	if popped != 1000 {
+		t.Errorf("弹出 %d 个; 期望 1000", popped)
+	}
+
+	// 栈作为非指针字段、外层结构体按值编码时也不能丢失数据
+	type history struct {
+		Undo SyncStack[string]
+	}
+	var h history
+	h.Undo.PushAll("a", "b")
+	data, err := json.Marshal(h)
+	if err != nil || string(data) != `{"Undo":["a","b"]}` {
+		t.Errorf("json.Marshal(结构体) = %s, %v; 期望 {\"Undo\":[\"a\",\"b\"]}", data, err)
+	}
+	var decoded history
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Undo.Len() != 2 {
+		t.Errorf("json.Unmarshal(结构体) 得到 %d 个元素, %v; 期望 2", decoded.Undo.Len(), err)
+	}
+	if data, _ := json.Marshal(history{}); string(data) != `{"Undo":[]}` {
+		t.Errorf("json.Marshal(零值) = %s; 期望 {\"Undo\":[]}", data)
+	}
+}
+
+// iterableContainer 所有集合都额外提供 Len 和 All，用于一致性测试
+type iterableContainer[T any] interface {
+	Container[T]
+	Len() int
+	All() iter.Seq[T]
+}
+
+// TestContainerConformance 对每种集合运行同一套 Container[T] 行为测试
+func TestContainerConformance(t *testing.T) {
+	implementations := map[string]func() iterableContainer[int]{
+		"Set":       func() iterableContainer[int] { return NewSet[int]() },
+		"Set(零值)":   func() iterableContainer[int] { return &Set[int]{} },
+		"Queue":     func() iterableContainer[int] { return &Queue[int]{} },
+		"Deque":     func() iterableContainer[int] { return &Deque[int]{} },
+		"List":      func() iterableContainer[int] { return &List[int]{} },
+		"DequeFunc": func() iterableContainer[int] { return NewDequeFunc(func(a, b int) bool { return a == b }) },
+	}
+	for name, newContainer := range implementations {
+		t.Run(name, func(t *testing.T) {
+			c := newContainer()
+			if c.Len() != 0 || c.Contains(1) || c.Remove(1) {
+				t.Fatal("新建的集合应该为空")
+			}
+			for i := 0; i < 100; i++ {
+				c.Add(i)
+			}
+			if c.Len() != 100 {
+				t.Errorf("Len() = %d; 期望 100", c.Len())
+			}
+			for i := 0; i < 100; i += 2 {
+				if !c.Remove(i) {
+					t.Errorf("Remove(%d) = false; 期望 true", i)
+				}
+			}
+			if c.Remove(0) {
+				t.Error("重复 Remove 应该返回 false")
+			}
+			for i := 0; i < 100; i++ {
+				if c.Contains(i) != (i%2 == 1) {
+					t.Errorf("Contains(%d) = %t", i, c.Contains(i))
+				}
+			}
+			got := slices.Sorted(c.All())
+			if len(got) != 50 || got[0] != 1 || got[49] != 99 {
+				t.Errorf("All() = %v; 期望 1~99 的奇数", got)
+			}
+			for range c.All() {
+				break // 提前结束遍历不应该 panic
+			}
+		})
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a := NewSet(1, 2, 3, 4)
+	b := NewSet(3, 4, 5)
+	if got := slices.Sorted(a.Union(b).All()); fmt.Sprint(got) != "[1 2 3 4 5]" {
+		t.Errorf("Union = %v", got)
+	}
+	if got := slices.Sorted(a.Intersection(b).All()); fmt.Sprint(got) != "[3 4]" {
+		t.Errorf("Intersection = %v", got)
+	}
+	if got := slices.Sorted(a.Difference(b).All()); fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("Difference = %v", got)
+	}
+
+	// nil 集合视为空集合
+	if got := slices.Sorted(a.Union(nil).All()); fmt.Sprint(got) != "[1 2 3 4]" {
+		t.Errorf("Union(nil) = %v; 期望 [1 2 3 4]", got)
+	}
+	if got := a.Intersection(nil); got.Len() != 0 {
+		t.Errorf("Intersection(nil) = %v; 期望空集合", slices.Collect(got.All()))
+	}
+	if got := slices.Sorted(a.Difference(nil).All()); fmt.Sprint(got) != "[1 2 3 4]" {
+		t.Errorf("Difference(nil) = %v; 期望 [1 2 3 4]", got)
+	}
+}
+
+func TestDequeAndList(t *testing.T) {
+	// 头尾交替操作，让环形缓冲区多次回绕和扩容
+	var d Deque[int]
+	var l List[int]
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			d.PushFront(i)
+			l.PushFront(i)
+		} else {
+			d.PushBack(i)
+			l.PushBack(i)
+		}
+	}
+	want := "[18 16 14 12 10 8 6 4 2 0 1 3 5 7 9 11 13 15 17 19]"
+	if got := fmt.Sprint(slices.Collect(d.All())); got != want {
+		t.Errorf("Deque = %s; 期望 %s", got, want)
+	}
+	if got := fmt.Sprint(slices.Collect(l.All())); got != want {
+		t.Errorf("List = %s; 期望 %s", got, want)
+	}
+	if fmt.Sprint(slices.Collect(d.Backward())) != fmt.Sprint(slices.Collect(l.Backward())) {
+		t.Error("Deque 与 List 反向遍历的结果不一致")
+	}
+	if d.At(10) != 1 {
+		t.Errorf("At(10) = %d; 期望 1", d.At(10))
+	}
+	front, _ := d.PopFront()
+	back, _ := l.PopBack()
+	if front != 18 || back != 19 {
+		t.Errorf("PopFront/PopBack = %d/%d; 期望 18/19", front, back)
+	}
+
+	var q Queue[string]
+	q.Enqueue("a")
+	q.Enqueue("b")
+	if item, _ := q.Dequeue(); item != "a" {
+		t.Errorf("Dequeue() = %s; 期望 a", item)
+	}
+
+	// 不可比较的元素：提供比较函数后同样满足 Container
+	var c Container[[]int] = NewQueueFunc(slices.Equal[[]int])
+	c.Add([]int{1, 2})
+	c.Add([]int{3})
+	if !c.Contains([]int{3}) || !c.Remove([]int{1, 2}) || c.Contains([]int{1, 2}) {
+		t.Error("NewQueueFunc 创建的队列应该使用比较函数查找和删除切片元素")
+	}
+	funcs := NewListFunc(func(a, b func() int) bool { return a() == b() })
+	funcs.PushBack(func() int { return 1 })
+	if !funcs.Contains(func() int { return 1 }) {
+		t.Error("NewListFunc 创建的链表应该使用比较函数")
+	}
+}
+
+// version 实现了 Comparable[version] 的版本号
+type version struct {
+	major, minor int
+}
+
+func (v version) Compare(other version) int {
+	if c := cmp.Compare(v.major, other.major); c != 0 {
+		return c
+	}
+	return cmp.Compare(v.minor, other.minor)
+}
+
+// checkAVL 检查子树的高度、大小和平衡性，返回子树高度
+func checkAVL[K, V any](t *testing.T, n *avlNode[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	l, r := checkAVL(t, n.left), checkAVL(t, n.right)
+	if l-r > 1 || r-l > 1 || n.height != 1+max(l, r) || n.size != 1+n.left.getSize()+n.right.getSize() {
+		t.Fatalf("节点 %v 不满足 AVL 性质", n.key)
+	}
+	return n.height
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap[int, string]()
+	reference := map[int]string{}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		key := r.Intn(500)
+		if r.Intn(3) == 0 {
+			_, existed := reference[key]
+			if m.Delete(key) != existed {
+				t.Fatalf("Delete(%d) 与预期不符", key)
+			}
+			delete(reference, key)
+		} else {
+			value := fmt.Sprint(i)
+			_, existed := reference[key]
+			if m.Put(key, value) == existed {
+				t.Fatalf("Put(%d) 与预期不符", key)
+			}
+			reference[key] = value
+		}
+	}
+	checkAVL(t, m.root)
+
+	keys := slices.Sorted(maps.Keys(reference))
+	if m.Len() != len(keys) || !slices.Equal(slices.Collect(m.Keys()), keys) {
+		t.Fatalf("有序遍历结果与参照不一致")
+	}
+	for i, key := range keys {
+		if v, ok := m.Get(key); !ok || v != reference[key] {
+			t.Errorf("Get(%d) = %s, %t", key, v, ok)
+		}
+		if m.Rank(key) != i {
+			t.Errorf("Rank(%d) = %d; 期望 %d", key, m.Rank(key), i)
+		}
+		if k, _, _ := m.Select(i); k != key {
+			t.Errorf("Select(%d) = %d; 期望 %d", i, k, key)
+		}
+	}
+	for probe := -1; probe <= 500; probe++ {
+		i, found := slices.BinarySearch(keys, probe)
+		if floor, _, ok := m.Floor(probe); found && floor != probe || !found && (i > 0) != ok || !found && ok && floor != keys[i-1] {
+			t.Errorf("Floor(%d) = %d, %t", probe, floor, ok)
+		}
+		if ceiling, _, ok := m.Ceiling(probe); found && ceiling != probe || !found && (i < len(keys)) != ok || !found && ok && ceiling != keys[i] {
+			t.Errorf("Ceiling(%d) = %d, %t", probe, ceiling, ok)
+		}
+	}
+
+	var ranged []int
+	for k := range m.Range(100, 200) {
+		ranged = append(ranged, k)
+	}
+	lo, _ := slices.BinarySearch(keys, 100)
+	hi, _ := slices.BinarySearch(keys, 200)
+	if !slices.Equal(ranged, keys[lo:hi]) {
+		t.Errorf("Range(100, 200) = %v; 期望 %v", ranged, keys[lo:hi])
+	}
+}
+
+func TestOrderedSetComparable(t *testing.T) {
+	s := NewOrderedSetFunc(version{1, 10}, version{1, 2}, version{2, 0}, version{0, 9})
+	if got := fmt.Sprint(slices.Collect(s.All())); got != "[{0 9} {1 2} {1 10} {2 0}]" {
+		t.Errorf("All() = %s", got)
+	}
+	if v, ok := s.Floor(version{1, 5}); !ok || v != (version{1, 2}) {
+		t.Errorf("Floor({1 5}) = %v, %t; 期望 {1 2}", v, ok)
+	}
+	if v, ok := s.Ceiling(version{1, 5}); !ok || v != (version{1, 10}) {
+		t.Errorf("Ceiling({1 5}) = %v, %t; 期望 {1 10}", v, ok)
+	}
+	if s.Rank(version{2, 0}) != 3 {
+		t.Errorf("Rank({2 0}) = %d; 期望 3", s.Rank(version{2, 0}))
+	}
+	if got := fmt.Sprint(slices.Collect(s.Range(version{1, 0}, version{2, 0}))); got != "[{1 2} {1 10}]" {
+		t.Errorf("Range = %s", got)
+	}
+
+	ints := NewOrderedSet(5, 1, 3)
+	if v, _ := ints.Select(1); v != 3 || !ints.Contains(5) || ints.Remove(4) {
+		t.Error("NewOrderedSet 行为不正确")
+	}
+}
+
+func TestPair(t *testing.T) {
+	var pair PairInterface[string, int] = &Pair[string, int]{}
+	pair.SetKey("age")
+	pair.SetValue(18)
+	if pair.Key() != "age" || pair.Value() != 18 {
+		t.Errorf("Pair = %v; 期望 (age, 18)", pair)
+	}
+}
+
+func TestLinkedMap(t *testing.T) {
+	m := NewLinkedMap[string, int]()
+	m.Set("c", 3)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("a", 10) // 更新不改变位置
+	if got := fmt.Sprint(slices.Collect(m.Pairs())); got != "[(c, 3) (a, 10) (b, 2)]" {
+		t.Errorf("Pairs() = %s", got)
+	}
+	if pair, ok := m.Delete("c"); !ok || pair.Value() != 3 {
+		t.Errorf("Delete(c) = %v, %t", pair, ok)
+	}
+	if oldest, _ := m.Oldest(); oldest.Key() != "a" {
+		t.Errorf("Oldest() = %v; 期望 a", oldest)
+	}
+}
+
+func TestLRUMap(t *testing.T) {
+	var evicted []string
+	lru := NewLRUMap(3, func(pair Pair[string, int]) {
+		evicted = append(evicted, pair.Key())
+	})
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	lru.Set("c", 3)
+	lru.Get("a")     // a 变为最近访问
+	lru.Peek("b")    // Peek 不改变顺序
+	lru.Set("d", 4)  // 淘汰 b
+	lru.Set("c", 30) // 更新也算访问
+	lru.Set("e", 5)  // 淘汰 a
+	if fmt.Sprint(evicted) != "[b a]" {
+		t.Errorf("淘汰顺序 = %v; 期望 [b a]", evicted)
+	}
+
+	var keys []string
+	for k := range lru.All() {
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[e c d]" {
+		t.Errorf("最近访问顺序 = %v; 期望 [e c d]", keys)
+	}
+
+	lru.Resize(1)
+	if lru.Len() != 1 || fmt.Sprint(evicted) != "[b a d c]" {
+		t.Errorf("Resize(1) 之后 Len() = %d, 淘汰 %v", lru.Len(), evicted)
+	}
+}
+
+// naturals 从 0 开始的无限序列，用于验证组合函数是惰性的
+func naturals() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func TestSeqCombinators(t *testing.T) {
+	evens := Filter(naturals(), func(n int) bool { return n%2 == 0 })
+	squares := Map(evens, func(n int) string { return fmt.Sprint(n * n) })
+	if got := slices.Collect(Take(Skip(squares, 1), 3)); !slices.Equal(got, []string{"4", "16", "36"}) {
+		t.Errorf("Map/Filter/Skip/Take = %v; 期望 [4 16 36]", got)
+	}
+
+	sum := Reduce(Take(naturals(), 5), 0, func(acc, n int) int { return acc + n })
+	if sum != 10 {
+		t.Errorf("Reduce = %d; 期望 10", sum)
+	}
+
+	zipped := maps.Collect(Zip(slices.Values([]string{"a", "b", "c"}), naturals()))
+	if !maps.Equal(zipped, map[string]int{"a": 0, "b": 1, "c": 2}) {
+		t.Errorf("Zip = %v", zipped)
+	}
+
+	if got := fmt.Sprint(slices.Collect(Chunk(Take(naturals(), 5), 2))); got != "[[0 1] [2 3] [4]]" {
+		t.Errorf("Chunk = %s", got)
+	}
+	windows := slices.Collect(Window(Take(naturals(), 4), 3))
+	if got := fmt.Sprint(windows); got != "[[0 1 2] [1 2 3]]" {
+		t.Errorf("Window = %s", got)
+	}
+	windows[0][0] = 100 // 窗口之间不共享底层数组
+	if windows[1][0] != 1 {
+		t.Errorf("Window 返回的切片互相影响: %v", windows)
+	}
+
+	distinct := slices.Collect(Distinct(slices.Values([]int{3, 1, 3, 2, 1})))
+	if !slices.Equal(distinct, []int{3, 1, 2}) {
+		t.Errorf("Distinct = %v; 期望 [3 1 2]", distinct)
+	}
+
+	groups := GroupBy(Take(naturals(), 7), func(n int) int { return n % 3 })
+	if !slices.Equal(groups[0], []int{0, 3, 6}) || !slices.Equal(groups[2], []int{2, 5}) {
+		t.Errorf("GroupBy = %v", groups)
+	}
+}
+
+func TestSeqInterop(t *testing.T) {
+	ages := map[string]int{"tom": 18, "amy": 30, "bob": 25}
+	adults := Filter2(maps.All(ages), func(_ string, age int) bool { return age > 20 })
+	if got := slices.Sorted(Keys(adults)); !slices.Equal(got, []string{"amy", "bob"}) {
+		t.Errorf("Filter2/Keys = %v", got)
+	}
+	if got := slices.Sorted(Values(maps.All(ages))); !slices.Equal(got, []int{18, 25, 30}) {
+		t.Errorf("Values = %v", got)
+	}
+	older := maps.Collect(Map2(maps.All(ages), func(name string, age int) (string, int) { return name, age + 1 }))
+	if older["tom"] != 19 {
+		t.Errorf("Map2 = %v", older)
+	}
+	if roundTrip := maps.Collect(Unpair(Pairs(maps.All(ages)))); !maps.Equal(roundTrip, ages) {
+		t.Errorf("Pairs/Unpair = %v", roundTrip)
+	}
+	// 键不要求可比较
+	var groups iter.Seq2[[]int, string] = func(yield func([]int, string) bool) {
+		_ = yield([]int{1, 2}, "a") && yield([]int{3}, "b")
+	}
+	var unpaired []string
+	for k, v := range Unpair(Pairs(groups)) {
+		unpaired = append(unpaired, fmt.Sprintf("%v=%s", k, v))
+	}
+	if fmt.Sprint(unpaired) != "[[1 2]=a [3]=b]" {
+		t.Errorf("Pairs/Unpair(切片键) = %v; 期望 [[1 2]=a [3]=b]", unpaired)
+	}
+
+	// 通道与序列互转；提前停止接收后取消 ctx，发送 goroutine 应退出并关闭通道
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ToChan(ctx, naturals(), 0)
+	if got := slices.Collect(Take(FromChan(ch), 3)); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("ToChan/FromChan = %v", got)
+	}
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,460 @@
+package generics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//////////////////////////////////////////////////////////////////
+// Handler实现了一个基于Go泛型的异步队列处理器，它是一个通用的生产者-消费者模式实现，可以处理任意类型的数据项。
+// 处理器内部可以启动多个消费者协程（worker pool），并支持在运行期间调整协程数量。
+// 队列由互斥锁保护，Push、Stop、Shutdown 之间不存在数据竞争。
+// 元素可以带有优先级和最早投递时间，优先级高的先处理，未到期的元素留在队列中（占用容量）直到到期。
+/////////////////////////////////////////////////////////////////
+
+type Handle[T any] func(item T)
+
+// ErrHandlerStopped 处理器未运行或正在关闭时推送元素返回的错误
+var ErrHandlerStopped = errors.New("处理器未运行")
+
+// OverflowPolicy 队列已满时 Push 的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowDropNewest OverflowPolicy = iota // 丢弃新元素，Push 返回 false（默认）
+	OverflowBlock                            // 阻塞直到队列有空位或处理器停止
+	OverflowDropOldest                       // 丢弃队列中最早的元素，为新元素腾出位置
+	OverflowSpill                            // 把新元素交给溢出回调，Push 返回 false
+)
+
+// handlerState 处理器的生命周期状态
+type handlerState int
+
+const (
+	stateIdle     handlerState = iota // 未启动或已停止
+	stateRunning                      // 运行中，接受新元素
+	stateDraining                     // 正在关闭，不再接受新元素，但会处理完队列中剩余的元素
+)
+
+type Handler[T any] struct {
+	mu       sync.Mutex
+	state    handlerState
+	queue    entryQueue[T] // 待处理的元素
+	capacity int           // 队列容量
+	reserved int           // 正在写预写日志、已经占用队列位置但还没有进入队列的元素数
+	ready    chan struct{} // 队列或状态发生变化时关闭并替换，用于唤醒等待中的消费者
+	space    chan struct{} // 队列腾出空位或状态发生变化时关闭并替换，用于唤醒阻塞中的生产者
+	overflow OverflowPolicy
+	spill    func(item T)
+
+	retry      RetryPolicy
+	deadLetter func(letter DeadLetter[T])
+	runner     *runner[T]
+	metrics    handlerMetrics
+	wal        *wal[T] // 非 nil 时为持久化模式
+
+	workers int             // 期望的消费者协程数量
+	quits   []chan struct{} // 每个运行中的消费者协程对应一个退出信号
+	wg      *sync.WaitGroup // 本次运行启动的所有消费者协程
+}
+
+func NewHandler[T any]() *Handler[T] {
+	return &Handler[T]{workers: 1}
+}
+
+// SetWorkers 设置消费者协程数量，n 小于 1 时按 1 处理。
+// 处理器运行期间调用会立即启动新的协程，或让多余的协程在处理完当前元素后退出。
+// SetWorkers 不等待协程退出，可以在 Handle 中调用。
+func (h *Handler[T]) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.workers = n
+	if h.state == stateRunning {
+		h.resize()
+	}
+}
+
+// Workers 返回当前配置的消费者协程数量
+func (h *Handler[T]) Workers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.workers
+}
+
+// SetOverflowPolicy 设置队列已满时 Push 的处理策略
+func (h *Handler[T]) SetOverflowPolicy(policy OverflowPolicy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.overflow = policy
+}
+
+// SetSpill 设置 OverflowSpill 策略下接收溢出元素的回调，回调在 Push 所在的协程中执行
+func (h *Handler[T]) SetSpill(spill func(item T)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.spill = spill
+}
+
+// Start 启动处理器，size 为队列容量（至少为 1）。如果处理器已在运行，会先调用 Stop，
+// 因此与 Stop 一样不能在 Handle 中调用。
+// handle 发生 panic 时会被恢复并视为处理失败，不会导致消费者协程退出。
+func (h *Handler[T]) Start(size int, handle Handle[T]) {
+	h.StartE(size, func(item T) error {
+		handle(item)
+		return nil
+	})
+}
+
+// StartE 与 Start 相同，但使用返回错误的处理函数，失败的元素按 SetRetry 的策略重试，
+// 重试耗尽后投递给 SetDeadLetter 设置的回调。
+func (h *Handler[T]) StartE(size int, handle HandleE[T]) {
+	h.start(size, &runner[T]{handle: handle})
+}
+
+// start 按给定的处理配置启动处理器，重试策略和死信回调取自当前设置
+func (h *Handler[T]) start(size int, r *runner[T]) {
+	h.Stop()
+	if size < 1 {
+		size = 1
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r.retry = h.retry
+	r.deadLetter = h.deadLetter
+	r.stopped = make(chan struct{})
+	r.wal = h.wal
+	h.state = stateRunning
+	h.capacity = size
+	h.ready = make(chan struct{})
+	h.space = make(chan struct{})
+	h.runner = r
+	if h.wal != nil {
+		for _, e := range h.wal.replay() {
+			h.queue.push(e)
+		}
+	}
+	h.wg = &sync.WaitGroup{}
+	h.resize()
+}
+
+// resize 让运行中的协程数量与 workers 保持一致，调用方需持有 mu
+func (h *Handler[T]) resize() {
+	for len(h.quits) < h.workers {
+		quit := make(chan struct{})
+		h.quits = append(h.quits, quit)
+		h.wg.Add(1)
+		go h.work(h.wg, h.runner, quit)
+	}
+	for len(h.quits) > h.workers {
+		last := len(h.quits) - 1
+		close(h.quits[last])
+		h.quits = h.quits[:last]
+	}
+}
+
+func (h *Handler[T]) work(wg *sync.WaitGroup, r *runner[T], quit <-chan struct{}) {
+	defer wg.Done()
+	for {
+		e, ok := h.next(quit, time.Time{})
+		if !ok {
+			return
+		}
+		if r.batch != nil {
+			batch := h.collect(e, r, quit)
+			start := time.Now()
+			ok = r.processBatch(batch)
+			h.metrics.observe(time.Since(start), len(batch), ok)
+			continue
+		}
+		start := time.Now()
+		ok = r.process(e)
+		h.metrics.observe(time.Since(start), 1, ok)
+	}
+}
+
+// next 取出下一个可以投递的元素，没有时阻塞等待（包括等待延迟元素到期）；
+// 协程需要退出或到达 deadline（非零值时）返回 false
+func (h *Handler[T]) next(quit <-chan struct{}, deadline time.Time) (e entry[T], ok bool) {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		h.mu.Lock()
+		select {
+		case <-quit:
+			h.mu.Unlock()
+			return e, false
+		default:
+		}
+		e, due, ok := h.queue.pop(time.Now())
+		if ok {
+			h.release()
+			h.mu.Unlock()
+			return e, true
+		}
+		if h.state != stateRunning && h.queue.len() == 0 {
+			h.mu.Unlock()
+			return e, false
+		}
+		ready := h.ready
+		h.mu.Unlock()
+
+		var wake <-chan time.Time // 最早的延迟元素到期
+		var timer *time.Timer
+		if !due.IsZero() {
+			timer = time.NewTimer(time.Until(due))
+			wake = timer.C
+		}
+		exit := false
+		select {
+		case <-ready:
+		case <-wake:
+		case <-quit:
+			exit = true
+		case <-timeout:
+			exit = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if exit {
+			return e, false
+		}
+	}
+}
+
+// notify 唤醒所有等待中的消费者，调用方需持有 mu
+func (h *Handler[T]) notify() {
+	close(h.ready)
+	h.ready = make(chan struct{})
+}
+
+// release 唤醒所有阻塞中的生产者，调用方需持有 mu
+func (h *Handler[T]) release() {
+	close(h.space)
+	h.space = make(chan struct{})
+}
+
+// halt 停止所有协程并按进入队列的顺序取出剩余的元素，调用方需持有 mu
+func (h *Handler[T]) halt() (rest []entry[T], wg *sync.WaitGroup) {
+	wg = h.wg
+	rest = h.queue.drain()
+	for _, quit := range h.quits {
+		close(quit)
+	}
+	h.quits = nil
+	h.wg = nil
+	if h.state != stateIdle {
+		h.release()
+		close(h.runner.stopped)
+	}
+	h.state = stateIdle
+	return rest, wg
+}
+
+// Stop 立即停止处理器：丢弃队列中尚未处理的元素，并等待正在执行的 Handle 调用返回。
+// 批处理模式下不丢弃元素：等正在攒的批次处理完后，队列中剩余的元素按 maxBatch 分批、在调用 Stop 的协程中交给 handle，
+// 此时失败的批次不再重试，直接进入死信。
+// 它会等待所有正在执行的 Handle 返回，在 Handle 中调用等于等待自己，会死锁；需要在 Handle 中停止时使用 Cancel。
+func (h *Handler[T]) Stop() {
+	h.mu.Lock()
+	rest, wg := h.halt()
+	r := h.runner
+	h.mu.Unlock()
+	if wg != nil {
+		wg.Wait()
+	}
+	if r != nil && r.batch != nil {
+		h.flush(r, rest)
+	}
+}
+
+// Cancel 发出停止信号后立即返回：不再接受新元素，移出并返回队列中尚未处理的元素，
+// 不等待正在执行的 Handle，各协程在当前的 Handle 返回后退出。Cancel 不等待任何协程，可以在 Handle 中调用。
+// 持久化模式下返回的元素没有被确认，下次启动时会重放
+func (h *Handler[T]) Cancel() []T {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rest, _ := h.halt()
+	return items(rest)
+}
+
+// Shutdown 优雅关闭处理器：立即停止接受新元素，等待消费者处理完队列中的所有元素后返回。
+// 如果 ctx 先于排空结束，剩余未处理的元素会被移出队列并连同 ctx.Err() 一起返回，
+// 此时正在执行的 Handle 调用会在后台继续完成，之后协程退出。
+// 与 Stop 一样不能在 Handle 中调用。
+func (h *Handler[T]) Shutdown(ctx context.Context) ([]T, error) {
+	h.mu.Lock()
+	if h.state != stateRunning {
+		h.mu.Unlock()
+		return nil, nil
+	}
+	h.state = stateDraining
+	h.notify()  // 唤醒空闲的消费者，让它们在队列排空后退出
+	h.release() // 唤醒阻塞中的生产者，让它们返回 ErrHandlerStopped
+	wg := h.wg
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		h.mu.Lock()
+		var rest []entry[T]
+		if h.wg == wg {
+			rest, _ = h.halt()
+		}
+		h.mu.Unlock()
+		return items(rest), nil
+	case <-ctx.Done():
+		h.mu.Lock()
+		var rest []entry[T]
+		if h.wg == wg {
+			rest, _ = h.halt()
+		}
+		h.mu.Unlock()
+		return items(rest), ctx.Err()
+	}
+}
+
+// Push 推送元素，成功进入队列时返回 true，opts 可以设置元素的优先级和最早投递时间。
+// 队列已满时按照 SetOverflowPolicy 设置的策略处理；处理器未运行时返回 false。
+func (h *Handler[T]) Push(item T, opts ...PushOption) bool {
+	o := newPushOptions(opts)
+	h.mu.Lock()
+	if h.state != stateRunning {
+		h.mu.Unlock()
+		h.metrics.rejected.Add(1)
+		return false
+	}
+	if !h.full() {
+		err := h.enqueue(item, o)
+		h.mu.Unlock()
+		if err != nil {
+			h.metrics.rejected.Add(1)
+		}
+		return err == nil
+	}
+
+	switch h.overflow {
+	case OverflowBlock:
+		h.mu.Unlock()
+		return h.PushContext(context.Background(), item, opts...) == nil
+	case OverflowDropOldest:
+		if err := h.enqueue(item, o); err != nil {
+			h.mu.Unlock()
+			h.metrics.rejected.Add(1)
+			return false
+		}
+		// 持久化模式下 enqueue 期间会暂时释放 mu，队列可能已经被消费者腾出了位置
+		if h.queue.len()+h.reserved <= h.capacity {
+			h.mu.Unlock()
+			return true
+		}
+		dropped := h.queue.removeOldest()
+		r := h.runner // start 会在 mu 下替换 runner，解锁前取出本次运行的
+		h.mu.Unlock()
+		h.metrics.dropped.Add(1)
+		r.ack(dropped)
+		return true
+	case OverflowSpill:
+		spill := h.spill
+		h.mu.Unlock()
+		h.metrics.rejected.Add(1)
+		if spill != nil {
+			spill(item)
+		}
+		return false
+	default:
+		h.mu.Unlock()
+		h.metrics.rejected.Add(1)
+		return false
+	}
+}
+
+// items 取出元素中的数据
+func items[T any](entries []entry[T]) []T {
+	var result []T
+	for _, e := range entries {
+		result = append(result, e.item)
+	}
+	return result
+}
+
+// PushContext 阻塞地推送元素，不受溢出策略影响：队列已满时一直等待空位，
+// 直到 ctx 结束（返回 ctx.Err()）或处理器停止（返回 ErrHandlerStopped）。
+func (h *Handler[T]) PushContext(ctx context.Context, item T, opts ...PushOption) error {
+	o := newPushOptions(opts)
+	for {
+		h.mu.Lock()
+		if h.state != stateRunning {
+			h.mu.Unlock()
+			h.metrics.rejected.Add(1)
+			return ErrHandlerStopped
+		}
+		if !h.full() {
+			err := h.enqueue(item, o)
+			h.mu.Unlock()
+			if err != nil {
+				h.metrics.rejected.Add(1)
+			}
+			return err
+		}
+		space := h.space
+		h.mu.Unlock()
+
+		select {
+		case <-space:
+		case <-ctx.Done():
+			h.metrics.rejected.Add(1)
+			return ctx.Err()
+		}
+	}
+}
+
+// full 队列（包括正在写预写日志的元素）是否已满，调用方需持有 mu
+func (h *Handler[T]) full() bool {
+	return h.queue.len()+h.reserved >= h.capacity
+}
+
+// enqueue 把元素加入队列并唤醒消费者。调用方需持有 mu，返回时仍然持有。
+// 持久化模式下先写入预写日志：写日志和同步磁盘期间释放 mu 并占用一个队列位置，
+// 其他 Push 和消费者不必排队等待磁盘；如果处理器在此期间停止，确认这条记录并返回 ErrHandlerStopped。
+func (h *Handler[T]) enqueue(item T, o pushOptions) error {
+	e := entry[T]{item: item, pushOptions: o}
+	if w := h.wal; w != nil {
+		h.reserved++
+		h.mu.Unlock()
+		id, err := w.append(item, o)
+		h.mu.Lock()
+		h.reserved--
+		if err != nil {
+			return err
+		}
+		if h.state != stateRunning {
+			h.mu.Unlock()
+			w.ack(id)
+			w.settle(id)
+			h.mu.Lock()
+			return ErrHandlerStopped
+		}
+		w.settle(id)
+		e.id = id
+	}
+	h.metrics.accepted.Add(1)
+	h.queue.push(e)
+	h.notify()
+	return nil
+}
@@ -0,0 +1,107 @@
+package generics
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencySamples 计算延迟分位数时保留的最近样本数量
+const latencySamples = 1024
+
+// HandlerStats Handler 的运行统计快照
+type HandlerStats struct {
+	QueueDepth int // 队列中等待处理的元素数量（包括未到期的延迟元素）
+	Delayed    int // 队列中尚未到投递时间的元素数量
+	Capacity   int // 队列容量
+	Workers    int // 配置的消费者协程数量
+
+	Accepted  uint64 // 成功进入队列的元素数量
+	Rejected  uint64 // 被拒绝的元素数量（队列已满、处理器未运行、溢出回调、PushContext 超时）
+	Dropped   uint64 // 被 OverflowDropOldest 策略挤出队列的元素数量
+	Processed uint64 // 处理成功的元素数量
+	Failed    uint64 // 重试耗尽后处理失败的元素数量
+
+	// 最近 1024 次处理耗时（包含重试等待，批处理模式下按批统计）的分位数
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	LatencyP99 time.Duration
+}
+
+// handlerMetrics 计数器使用原子操作，延迟样本使用独立的锁，不与队列锁竞争
+type handlerMetrics struct {
+	accepted  atomic.Uint64
+	rejected  atomic.Uint64
+	dropped   atomic.Uint64
+	processed atomic.Uint64
+	failed    atomic.Uint64
+
+	mu        sync.Mutex
+	latencies [latencySamples]time.Duration // 环形缓冲区
+	next      int
+	count     int
+}
+
+// observe 记录一次处理（n 个元素）的结果和耗时
+func (m *handlerMetrics) observe(d time.Duration, n int, ok bool) {
+	if ok {
+		m.processed.Add(uint64(n))
+	} else {
+		m.failed.Add(uint64(n))
+	}
+	m.mu.Lock()
+	m.latencies[m.next] = d
+	m.next = (m.next + 1) % latencySamples
+	m.count = min(m.count+1, latencySamples)
+	m.mu.Unlock()
+}
+
+// fill 把计数器累加到快照中，并返回延迟样本的副本
+func (m *handlerMetrics) fill(stats *HandlerStats) []time.Duration {
+	stats.Accepted += m.accepted.Load()
+	stats.Rejected += m.rejected.Load()
+	stats.Dropped += m.dropped.Load()
+	stats.Processed += m.processed.Load()
+	stats.Failed += m.failed.Load()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.latencies[:m.count])
+}
+
+// setLatency 根据延迟样本计算快照中的分位数
+func (stats *HandlerStats) setLatency(samples []time.Duration) {
+	if len(samples) == 0 {
+		return
+	}
+	slices.Sort(samples)
+	stats.LatencyP50 = percentile(samples, 0.50)
+	stats.LatencyP90 = percentile(samples, 0.90)
+	stats.LatencyP99 = percentile(samples, 0.99)
+}
+
+// percentile 使用最近秩法从已排序的样本中取分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// Stats 返回处理器的统计快照，计数器从创建处理器开始累计，重启不会清零。
+// 开销为复制并排序至多 1024 个样本，适合每秒轮询。
+func (h *Handler[T]) Stats() HandlerStats {
+	var stats HandlerStats
+	stats.setLatency(h.collectStats(&stats))
+	return stats
+}
+
+// collectStats 把处理器的状态和计数器累加到 stats 中，并返回延迟样本
+func (h *Handler[T]) collectStats(stats *HandlerStats) []time.Duration {
+	h.mu.Lock()
+	stats.QueueDepth += h.queue.len()
+	stats.Delayed += len(h.queue.delayed)
+	stats.Capacity += h.capacity
+	stats.Workers += h.workers
+	h.mu.Unlock()
+	return h.metrics.fill(stats)
+}
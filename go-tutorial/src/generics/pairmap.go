@@ -0,0 +1,207 @@
+package generics
+
+import "iter"
+
+// linkedEntry LinkedMap 中的条目：一个 Pair 加上双向链表指针
+type linkedEntry[K comparable, V any] struct {
+	Pair[K, V]
+	prev, next *linkedEntry[K, V]
+}
+
+// LinkedMap 按插入顺序遍历的映射，条目以 Pair 的形式保存在双向链表中，
+// 通过哈希表定位条目，查找、插入、删除都是 O(1)。零值不可用，请使用 NewLinkedMap 创建
+type LinkedMap[K comparable, V any] struct {
+	entries    map[K]*linkedEntry[K, V]
+	head, tail *linkedEntry[K, V] // head 最早插入，tail 最晚插入
+}
+
+func NewLinkedMap[K comparable, V any]() *LinkedMap[K, V] {
+	return &LinkedMap[K, V]{entries: make(map[K]*linkedEntry[K, V])}
+}
+
+func (m *LinkedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+func (m *LinkedMap[K, V]) Get(key K) (V, bool) {
+	if e, ok := m.entries[key]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set 设置键对应的值，已存在的键保持原来的位置，返回是否新增了键
+func (m *LinkedMap[K, V]) Set(key K, value V) bool {
+	if e, ok := m.entries[key]; ok {
+		e.SetValue(value)
+		return false
+	}
+	e := &linkedEntry[K, V]{Pair: NewPair(key, value)}
+	m.entries[key] = e
+	m.pushBack(e)
+	return true
+}
+
+// Delete 删除键，返回被删除的条目
+func (m *LinkedMap[K, V]) Delete(key K) (Pair[K, V], bool) {
+	e, ok := m.entries[key]
+	if !ok {
+		return Pair[K, V]{}, false
+	}
+	delete(m.entries, key)
+	m.unlink(e)
+	return e.Pair, true
+}
+
+// Oldest 返回最早插入的条目
+func (m *LinkedMap[K, V]) Oldest() (Pair[K, V], bool) {
+	if m.head == nil {
+		return Pair[K, V]{}, false
+	}
+	return m.head.Pair, true
+}
+
+// Newest 返回最晚插入的条目
+func (m *LinkedMap[K, V]) Newest() (Pair[K, V], bool) {
+	if m.tail == nil {
+		return Pair[K, V]{}, false
+	}
+	return m.tail.Pair, true
+}
+
+// All 按插入顺序遍历条目
+func (m *LinkedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.head; e != nil; e = e.next {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward 从最晚插入的条目开始遍历
+func (m *LinkedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.tail; e != nil; e = e.prev {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Pairs 按插入顺序以 Pair 的形式遍历条目
+func (m *LinkedMap[K, V]) Pairs() iter.Seq[Pair[K, V]] {
+	return func(yield func(Pair[K, V]) bool) {
+		for e := m.head; e != nil; e = e.next {
+			if !yield(e.Pair) {
+				return
+			}
+		}
+	}
+}
+
+// moveToBack 把已存在的键移动到末尾，就像刚刚插入一样
+func (m *LinkedMap[K, V]) moveToBack(key K) {
+	if e, ok := m.entries[key]; ok && e != m.tail {
+		m.unlink(e)
+		m.pushBack(e)
+	}
+}
+
+func (m *LinkedMap[K, V]) pushBack(e *linkedEntry[K, V]) {
+	e.prev = m.tail
+	if m.tail != nil {
+		m.tail.next = e
+	} else {
+		m.head = e
+	}
+	m.tail = e
+}
+
+func (m *LinkedMap[K, V]) unlink(e *linkedEntry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		m.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		m.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+// LRUMap 容量有限的最近最少使用（LRU）映射，超过容量时淘汰最久未访问的条目。
+// 基于 LinkedMap 实现：链表头部是最久未访问的条目，尾部是最近访问的条目。非并发安全
+type LRUMap[K comparable, V any] struct {
+	items    *LinkedMap[K, V]
+	capacity int
+	onEvict  func(pair Pair[K, V])
+}
+
+// NewLRUMap 创建容量为 capacity（至少为 1）的 LRU 映射，onEvict 在条目因容量不足被淘汰时调用，可以为 nil
+func NewLRUMap[K comparable, V any](capacity int, onEvict func(pair Pair[K, V])) *LRUMap[K, V] {
+	return &LRUMap[K, V]{items: NewLinkedMap[K, V](), capacity: max(capacity, 1), onEvict: onEvict}
+}
+
+func (m *LRUMap[K, V]) Len() int {
+	return m.items.Len()
+}
+
+func (m *LRUMap[K, V]) Cap() int {
+	return m.capacity
+}
+
+// Get 读取键对应的值，并把它标记为最近访问
+func (m *LRUMap[K, V]) Get(key K) (V, bool) {
+	value, ok := m.items.Get(key)
+	if ok {
+		m.items.moveToBack(key)
+	}
+	return value, ok
+}
+
+// Peek 读取键对应的值，但不改变访问顺序
+func (m *LRUMap[K, V]) Peek(key K) (V, bool) {
+	return m.items.Get(key)
+}
+
+// Set 设置键对应的值并把它标记为最近访问，超过容量时淘汰最久未访问的条目
+func (m *LRUMap[K, V]) Set(key K, value V) {
+	if !m.items.Set(key, value) {
+		m.items.moveToBack(key)
+		return
+	}
+	m.evict()
+}
+
+// Delete 删除键，不会触发淘汰回调
+func (m *LRUMap[K, V]) Delete(key K) bool {
+	_, ok := m.items.Delete(key)
+	return ok
+}
+
+// Resize 调整容量，缩小容量时立即淘汰多出的条目
+func (m *LRUMap[K, V]) Resize(capacity int) {
+	m.capacity = max(capacity, 1)
+	m.evict()
+}
+
+func (m *LRUMap[K, V]) evict() {
+	for m.items.Len() > m.capacity {
+		oldest, _ := m.items.Oldest()
+		m.items.Delete(oldest.Key())
+		if m.onEvict != nil {
+			m.onEvict(oldest)
+		}
+	}
+}
+
+// All 从最近访问到最久未访问遍历条目，遍历不会改变访问顺序
+func (m *LRUMap[K, V]) All() iter.Seq2[K, V] {
+	return m.items.Backward()
+}
@@ -0,0 +1,130 @@
+package generics
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// PartitionedHandler 按 key 分区的处理器，由若干个单协程的 Handler（lane）组成。
+// 相同 key 的元素总是进入同一个 lane，并严格按推送顺序处理（重试也不会打乱顺序）；不同 lane 之间并行处理。
+// lane 的数量在创建时确定，运行期间不能调整，否则相同 key 的元素可能被分到不同的 lane。
+type PartitionedHandler[T any] struct {
+	key   func(item T) string
+	lanes []*Handler[T]
+}
+
+// NewPartitionedHandler 创建一个有 lanes 个分区的处理器（至少为 1），key 返回元素的分区键
+func NewPartitionedHandler[T any](lanes int, key func(item T) string) *PartitionedHandler[T] {
+	lanes = max(lanes, 1)
+	p := &PartitionedHandler[T]{key: key, lanes: make([]*Handler[T], lanes)}
+	for i := range p.lanes {
+		p.lanes[i] = NewHandler[T]()
+	}
+	return p
+}
+
+// Lanes 返回分区数量
+func (p *PartitionedHandler[T]) Lanes() int {
+	return len(p.lanes)
+}
+
+// lane 返回元素所属的分区，使用 FNV-1a 哈希保证同一个 key 始终落在同一个分区
+func (p *PartitionedHandler[T]) lane(item T) *Handler[T] {
+	hash := fnv.New32a()
+	hash.Write([]byte(p.key(item)))
+	return p.lanes[hash.Sum32()%uint32(len(p.lanes))]
+}
+
+// each 对每个分区执行 fn
+func (p *PartitionedHandler[T]) each(fn func(h *Handler[T])) {
+	for _, h := range p.lanes {
+		fn(h)
+	}
+}
+
+// SetOverflowPolicy 设置所有分区队列已满时的处理策略
+func (p *PartitionedHandler[T]) SetOverflowPolicy(policy OverflowPolicy) {
+	p.each(func(h *Handler[T]) { h.SetOverflowPolicy(policy) })
+}
+
+// SetSpill 设置所有分区的溢出回调
+func (p *PartitionedHandler[T]) SetSpill(spill func(item T)) {
+	p.each(func(h *Handler[T]) { h.SetSpill(spill) })
+}
+
+// SetRetry 设置所有分区的重试策略
+func (p *PartitionedHandler[T]) SetRetry(policy RetryPolicy) {
+	p.each(func(h *Handler[T]) { h.SetRetry(policy) })
+}
+
+// SetDeadLetter 设置所有分区的死信回调
+func (p *PartitionedHandler[T]) SetDeadLetter(sink func(letter DeadLetter[T])) {
+	p.each(func(h *Handler[T]) { h.SetDeadLetter(sink) })
+}
+
+// Start 启动所有分区，size 为每个分区的队列容量
+func (p *PartitionedHandler[T]) Start(size int, handle Handle[T]) {
+	p.each(func(h *Handler[T]) { h.Start(size, handle) })
+}
+
+// StartE 使用返回错误的处理函数启动所有分区
+func (p *PartitionedHandler[T]) StartE(size int, handle HandleE[T]) {
+	p.each(func(h *Handler[T]) { h.StartE(size, handle) })
+}
+
+// StartBatch 以批处理模式启动所有分区，每个批次只包含同一个分区的元素
+func (p *PartitionedHandler[T]) StartBatch(size, maxBatch int, interval time.Duration, handle BatchHandle[T]) {
+	p.each(func(h *Handler[T]) { h.StartBatch(size, maxBatch, interval, handle) })
+}
+
+// Push 把元素推送到所属的分区。优先级和投递时间只在分区内部生效，
+// 同一个 key 的元素会按优先级和投递时间处理，而不再严格按推送顺序
+func (p *PartitionedHandler[T]) Push(item T, opts ...PushOption) bool {
+	return p.lane(item).Push(item, opts...)
+}
+
+// PushContext 阻塞地把元素推送到所属的分区
+func (p *PartitionedHandler[T]) PushContext(ctx context.Context, item T, opts ...PushOption) error {
+	return p.lane(item).PushContext(ctx, item, opts...)
+}
+
+// Stop 停止所有分区
+func (p *PartitionedHandler[T]) Stop() {
+	p.each(func(h *Handler[T]) { h.Stop() })
+}
+
+// Shutdown 并行地优雅关闭所有分区，返回各分区未处理的元素和第一个错误
+func (p *PartitionedHandler[T]) Shutdown(ctx context.Context) ([]T, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var rest []T
+	var firstErr error
+	for _, h := range p.lanes {
+		wg.Add(1)
+		go func(h *Handler[T]) {
+			defer wg.Done()
+			items, err := h.Shutdown(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			rest = append(rest, items...)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}(h)
+	}
+	wg.Wait()
+	return rest, firstErr
+}
+
+// Stats 返回所有分区汇总后的统计快照，延迟分位数基于所有分区的样本计算
+func (p *PartitionedHandler[T]) Stats() HandlerStats {
+	var stats HandlerStats
+	var samples []time.Duration
+	p.each(func(h *Handler[T]) {
+		samples = append(samples, h.collectStats(&stats)...)
+	})
+	stats.setLatency(samples)
+	return stats
+}
@@ -0,0 +1,154 @@
+package generics
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"time"
+)
+
+// PushOption 推送元素时的可选参数
+type PushOption func(o *pushOptions)
+
+type pushOptions struct {
+	priority  int
+	notBefore time.Time
+}
+
+// WithPriority 设置元素的优先级，数值越大越先处理，默认为 0；同优先级的元素按推送顺序处理
+func WithPriority(priority int) PushOption {
+	return func(o *pushOptions) {
+		o.priority = priority
+	}
+}
+
+// WithNotBefore 设置元素的最早投递时间，到期之前元素留在队列中（占用容量）但不会被处理
+func WithNotBefore(t time.Time) PushOption {
+	return func(o *pushOptions) {
+		o.notBefore = t
+	}
+}
+
+// WithDelay 设置元素在 d 之后才投递，等价于 WithNotBefore(time.Now().Add(d))
+func WithDelay(d time.Duration) PushOption {
+	return WithNotBefore(time.Now().Add(d))
+}
+
+func newPushOptions(opts []PushOption) pushOptions {
+	var o pushOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// entry 队列中的元素
+type entry[T any] struct {
+	item T
+	pushOptions
+	seq uint64 // 进入队列的顺序
+	id  uint64 // 持久化模式下预写日志中的 id，用于确认处理完成
+}
+
+// entryQueue 待处理元素的队列：ready 中是可以立即投递的元素，按优先级从高到低、同优先级按推送顺序排列；
+// delayed 中是尚未到投递时间的元素，按投递时间排列
+type entryQueue[T any] struct {
+	ready   readyHeap[T]
+	delayed delayedHeap[T]
+	seq     uint64
+}
+
+func (q *entryQueue[T]) len() int {
+	return len(q.ready) + len(q.delayed)
+}
+
+// push 加入一个元素，并分配进入队列的顺序
+func (q *entryQueue[T]) push(e entry[T]) {
+	q.seq++
+	e.seq = q.seq
+	if e.notBefore.IsZero() {
+		heap.Push(&q.ready, e)
+	} else {
+		heap.Push(&q.delayed, e)
+	}
+}
+
+// pop 把已经到期的延迟元素移入 ready，然后取出优先级最高的元素；
+// 没有可投递的元素时返回 false，以及最早的延迟元素的投递时间（没有延迟元素时为零值）
+func (q *entryQueue[T]) pop(now time.Time) (e entry[T], due time.Time, ok bool) {
+	for len(q.delayed) > 0 && !q.delayed[0].notBefore.After(now) {
+		heap.Push(&q.ready, heap.Pop(&q.delayed))
+	}
+	if len(q.ready) > 0 {
+		return heap.Pop(&q.ready).(entry[T]), due, true
+	}
+	if len(q.delayed) > 0 {
+		due = q.delayed[0].notBefore
+	}
+	return e, due, false
+}
+
+// removeOldest 移除最早进入队列的元素（不论优先级和投递时间），队列不能为空
+func (q *entryQueue[T]) removeOldest() entry[T] {
+	oldest := func(h []entry[T]) int {
+		index := -1
+		for i := range h {
+			if index < 0 || h[i].seq < h[index].seq {
+				index = i
+			}
+		}
+		return index
+	}
+	r, d := oldest(q.ready), oldest(q.delayed)
+	if d < 0 || (r >= 0 && q.ready[r].seq < q.delayed[d].seq) {
+		return heap.Remove(&q.ready, r).(entry[T])
+	}
+	return heap.Remove(&q.delayed, d).(entry[T])
+}
+
+// drain 取出所有元素，按进入队列的顺序返回
+func (q *entryQueue[T]) drain() []entry[T] {
+	entries := append(slices.Clone(q.ready), q.delayed...)
+	slices.SortFunc(entries, func(a, b entry[T]) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	q.ready, q.delayed = nil, nil
+	return entries
+}
+
+// readyHeap 按优先级从高到低、同优先级按进入顺序排列的堆
+type readyHeap[T any] []entry[T]
+
+func (h readyHeap[T]) Len() int { return len(h) }
+func (h readyHeap[T]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h readyHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *readyHeap[T]) Push(x any)   { *h = append(*h, x.(entry[T])) }
+func (h *readyHeap[T]) Pop() any     { return popLast((*[]entry[T])(h)) }
+
+// delayedHeap 按投递时间排列的堆
+type delayedHeap[T any] []entry[T]
+
+func (h delayedHeap[T]) Len() int { return len(h) }
+func (h delayedHeap[T]) Less(i, j int) bool {
+	if !h[i].notBefore.Equal(h[j].notBefore) {
+		return h[i].notBefore.Before(h[j].notBefore)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h delayedHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *delayedHeap[T]) Push(x any)   { *h = append(*h, x.(entry[T])) }
+func (h *delayedHeap[T]) Pop() any     { return popLast((*[]entry[T])(h)) }
+
+// popLast 取出切片的最后一个元素，并清空该位置避免底层数组继续引用它
+func popLast[T any](s *[]entry[T]) entry[T] {
+	old := *s
+	e := old[len(old)-1]
+	old[len(old)-1] = entry[T]{}
+	*s = old[:len(old)-1]
+	return e
+}
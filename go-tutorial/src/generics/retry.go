@@ -0,0 +1,140 @@
+package generics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
+	"time"
+)
+
+// HandleE 返回错误的处理函数，返回非 nil 错误或发生 panic 都视为处理失败
+type HandleE[T any] func(item T) error
+
+// RetryPolicy 处理失败后的重试策略，零值表示不重试
+type RetryPolicy struct {
+	MaxAttempts    int           // 最多尝试次数（包含第一次），小于 1 时按 1 处理
+	InitialBackoff time.Duration // 第一次重试前的等待时间
+	MaxBackoff     time.Duration // 等待时间上限，0 表示不限制
+	Multiplier     float64       // 每次重试后等待时间的增长倍数，小于 1 时按 2 处理
+	Jitter         float64       // 随机抖动比例（0~1，超出范围时按边界处理），实际等待时间在 backoff*(1±Jitter) 之间
+}
+
+// backoff 计算第 retry 次重试（从 1 开始）前的等待时间
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	// 不限制上限时等待时间很快会超出 Duration 的范围，直接转换会溢出成负数，计时器立即触发，变成忙等重试
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// DeadLetter 重试耗尽后投递给死信回调的元素
+type DeadLetter[T any] struct {
+	Item     T
+	Err      error // 最后一次失败的错误
+	Attempts int   // 实际尝试的次数
+}
+
+// PanicError 处理函数发生 panic 时被转换成的错误
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("处理函数 panic: %v", e.Value)
+}
+
+// safeCall 调用处理函数，并把 panic 转换成 *PanicError，保证消费者协程不会因为单个元素退出
+func safeCall[T any](handle func(item T) error, item T) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return handle(item)
+}
+
+// SetRetry 设置处理失败后的重试策略，在下一次启动处理器时生效
+func (h *Handler[T]) SetRetry(policy RetryPolicy) {
+	// Jitter 大于 1 时抖动后的等待时间可能为负数，重试会立即触发
+	policy.Jitter = min(max(policy.Jitter, 0), 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retry = policy
+}
+
+// SetDeadLetter 设置死信回调，接收重试耗尽（或重试等待期间处理器被停止）的元素，在下一次启动处理器时生效
+func (h *Handler[T]) SetDeadLetter(sink func(letter DeadLetter[T])) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deadLetter = sink
+}
+
+// runner 一次运行中消费者协程使用的处理配置，在 Start 时确定
+type runner[T any] struct {
+	handle     HandleE[T]
+	batch      BatchHandle[T] // 非 nil 时为批处理模式
+	maxBatch   int
+	interval   time.Duration
+	retry      RetryPolicy
+	deadLetter func(letter DeadLetter[T])
+	stopped    chan struct{} // Stop 或 Shutdown 超时时关闭，用于中断重试等待
+	wal        *wal[T]
+}
+
+// process 处理单个元素：失败时按重试策略等待后重试，重试耗尽后投递到死信回调，返回最终是否成功
+func (r *runner[T]) process(e entry[T]) bool {
+	defer r.ack(e)
+	attempts, err := r.attempt(func() error { return safeCall(r.handle, e.item) })
+	if err != nil {
+		r.dead(e.item, err, attempts)
+		return false
+	}
+	return true
+}
+
+// ack 持久化模式下确认元素已经处理完成。确认失败时元素会在下次启动时重新投递，符合至少一次语义，因此忽略错误
+func (r *runner[T]) ack(e entry[T]) {
+	if r.wal != nil {
+		r.wal.ack(e.id)
+	}
+}
+
+// attempt 按重试策略调用 call，直到成功、重试耗尽或处理器停止，返回尝试次数和最后一次的错误
+func (r *runner[T]) attempt(call func() error) (attempts int, err error) {
+	maxAttempts := max(r.retry.MaxAttempts, 1)
+	for attempts = 1; ; attempts++ {
+		if err = call(); err == nil || attempts == maxAttempts {
+			return attempts, err
+		}
+		timer := time.NewTimer(r.retry.backoff(attempts))
+		select {
+		case <-timer.C:
+		case <-r.stopped:
+			timer.Stop()
+			return attempts, err
+		}
+	}
+}
+
+func (r *runner[T]) dead(item T, err error, attempts int) {
+	if r.deadLetter != nil {
+		r.deadLetter(DeadLetter[T]{Item: item, Err: err, Attempts: attempts})
+	}
+}
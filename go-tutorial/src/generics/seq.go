@@ -0,0 +1,252 @@
+package generics
+
+import (
+	"context"
+	"iter"
+	"slices"
+)
+
+// 基于 iter.Seq / iter.Seq2 的惰性迭代器组合函数。除 Reduce、GroupBy 等需要消费整个序列的函数外，
+// 其余函数都只在遍历时按需取值，不会生成中间切片，可以直接作用于无限序列。
+
+// Map 把序列中的每个元素转换为 f(v)
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Map2 把键值对序列中的每一对转换为 f(k, v)
+func Map2[K, V, K2, V2 any](seq iter.Seq2[K, V], f func(K, V) (K2, V2)) iter.Seq2[K2, V2] {
+	return func(yield func(K2, V2) bool) {
+		for k, v := range seq {
+			if !yield(f(k, v)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter 只保留满足 keep 的元素
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Filter2 只保留满足 keep 的键值对
+func Filter2[K, V any](seq iter.Seq2[K, V], keep func(K, V) bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if keep(k, v) && !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Reduce 从 initial 开始依次用 f 合并序列中的元素，返回最终结果
+func Reduce[T, U any](seq iter.Seq[T], initial U, f func(U, T) U) U {
+	result := initial
+	for v := range seq {
+		result = f(result, v)
+	}
+	return result
+}
+
+// Take 只取序列的前 n 个元素
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			i++
+			if i >= n {
+				return
+			}
+		}
+	}
+}
+
+// Skip 跳过序列的前 n 个元素
+func Skip[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i := 0
+		for v := range seq {
+			if i < n {
+				i++
+				continue
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Zip 把两个序列按位置配对，较短的序列结束时停止
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		next, stop := iter.Pull(b)
+		defer stop()
+		for va := range a {
+			vb, ok := next()
+			if !ok || !yield(va, vb) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk 把序列按 size 个元素一组切分，最后一组可能不足 size 个。每组都是新分配的切片，size 必须大于 0
+func Chunk[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	if size <= 0 {
+		panic("generics: Chunk 的 size 必须大于 0")
+	}
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, size)
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Window 返回长度为 size 的滑动窗口，每次向后移动一个元素；序列不足 size 个元素时不产生窗口。
+// 每个窗口都是新分配的切片，调用方可以安全保存，size 必须大于 0
+func Window[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	if size <= 0 {
+		panic("generics: Window 的 size 必须大于 0")
+	}
+	return func(yield func([]T) bool) {
+		window := make([]T, 0, size)
+		for v := range seq {
+			if len(window) == size {
+				window = window[1:]
+			}
+			window = append(window, v)
+			if len(window) == size && !yield(slices.Clone(window)) {
+				return
+			}
+		}
+	}
+}
+
+// GroupBy 按 key 对序列分组，组内保持原有顺序。需要消费整个序列
+func GroupBy[T any, K comparable](seq iter.Seq[T], key func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for v := range seq {
+		k := key(v)
+		groups[k] = append(groups[k], v)
+	}
+	return groups
+}
+
+// Distinct 去掉重复元素，只保留每个元素第一次出现的位置
+func Distinct[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		seen := make(map[T]struct{})
+		for v := range seq {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Keys 取出键值对序列中的键，可与 maps.All 配合使用
+func Keys[K, V any](seq iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values 取出键值对序列中的值
+func Values[K, V any](seq iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range seq {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Pairs 把键值对序列转换为 Pair 序列
+func Pairs[K, V any](seq iter.Seq2[K, V]) iter.Seq[Pair[K, V]] {
+	return func(yield func(Pair[K, V]) bool) {
+		for k, v := range seq {
+			if !yield(NewPair(k, v)) {
+				return
+			}
+		}
+	}
+}
+
+// Unpair 把 Pair 序列转换为键值对序列，键可比较时结果可以直接传给 maps.Collect
+func Unpair[K, V any](seq iter.Seq[Pair[K, V]]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for p := range seq {
+			if !yield(p.Key(), p.Value()) {
+				return
+			}
+		}
+	}
+}
+
+// FromChan 把通道转换为序列，通道关闭时序列结束
+func FromChan[T any](ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// ToChan 在新的 goroutine 中遍历序列并把元素发送到返回的通道，遍历结束后关闭通道。
+// ctx 取消后停止遍历，避免接收方提前退出时 goroutine 泄漏
+func ToChan[T any](ctx context.Context, seq iter.Seq[T], buffer int) <-chan T {
+	ch := make(chan T, buffer)
+	go func() {
+		defer close(ch)
+		for v := range seq {
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,409 @@
+package generics
+
+import (
+	"cmp"
+	"iter"
+)
+
+// 基于 AVL 树的有序容器。每个节点额外记录子树大小，使排名（Rank）和按排名取值（Select）也是 O(log n)。
+// 键可以是任意 cmp.Ordered 类型（NewOrderedMap），也可以是实现了 Comparable[K] 的类型（NewOrderedMapFunc）。
+
+type avlNode[K, V any] struct {
+	key         K
+	value       V
+	left, right *avlNode[K, V]
+	height      int
+	size        int // 以该节点为根的子树中的节点数
+}
+
+func (n *avlNode[K, V]) getHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *avlNode[K, V]) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// update 根据子节点重新计算高度和子树大小
+func (n *avlNode[K, V]) update() {
+	n.height = 1 + max(n.left.getHeight(), n.right.getHeight())
+	n.size = 1 + n.left.getSize() + n.right.getSize()
+}
+
+func (n *avlNode[K, V]) rotateRight() *avlNode[K, V] {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+func (n *avlNode[K, V]) rotateLeft() *avlNode[K, V] {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+// balance 更新节点并在左右子树高度差超过 1 时旋转
+func (n *avlNode[K, V]) balance() *avlNode[K, V] {
+	n.update()
+	switch diff := n.left.getHeight() - n.right.getHeight(); {
+	case diff > 1:
+		if n.left.left.getHeight() < n.left.right.getHeight() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case diff < -1:
+		if n.right.right.getHeight() < n.right.left.getHeight() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// removeMin 删除子树中的最小节点，返回新的子树根和被删除的节点
+func (n *avlNode[K, V]) removeMin() (*avlNode[K, V], *avlNode[K, V]) {
+	if n.left == nil {
+		return n.right, n
+	}
+	var smallest *avlNode[K, V]
+	n.left, smallest = n.left.removeMin()
+	return n.balance(), smallest
+}
+
+// OrderedMap 按键排序的映射，零值不可用，请使用 NewOrderedMap 或 NewOrderedMapFunc 创建
+type OrderedMap[K, V any] struct {
+	root    *avlNode[K, V]
+	compare func(a, b K) int
+}
+
+// NewOrderedMap 创建键为 cmp.Ordered 类型的有序映射
+func NewOrderedMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{compare: cmp.Compare[K]}
+}
+
+// NewOrderedMapFunc 创建键实现了 Comparable[K] 的有序映射，使用 a.Compare(b) 决定顺序
+func NewOrderedMapFunc[K Comparable[K], V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{compare: func(a, b K) int { return a.Compare(b) }}
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return m.root.getSize()
+}
+
+// Put 设置键对应的值，返回是否新增了键
+func (m *OrderedMap[K, V]) Put(key K, value V) bool {
+	var added bool
+	m.root, added = m.put(m.root, key, value)
+	return added
+}
+
+func (m *OrderedMap[K, V]) put(n *avlNode[K, V], key K, value V) (*avlNode[K, V], bool) {
+	if n == nil {
+		return &avlNode[K, V]{key: key, value: value, height: 1, size: 1}, true
+	}
+	var added bool
+	switch c := m.compare(key, n.key); {
+	case c < 0:
+		n.left, added = m.put(n.left, key, value)
+	case c > 0:
+		n.right, added = m.put(n.right, key, value)
+	default:
+		n.value = value
+		return n, false
+	}
+	return n.balance(), added
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	n := m.root
+	for n != nil {
+		switch c := m.compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete 删除键，返回键是否存在
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	var removed bool
+	m.root, removed = m.delete(m.root, key)
+	return removed
+}
+
+func (m *OrderedMap[K, V]) delete(n *avlNode[K, V], key K) (*avlNode[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	var removed bool
+	switch c := m.compare(key, n.key); {
+	case c < 0:
+		n.left, removed = m.delete(n.left, key)
+	case c > 0:
+		n.right, removed = m.delete(n.right, key)
+	default:
+		if n.left == nil {
+			return n.right, true
+		}
+		if n.right == nil {
+			return n.left, true
+		}
+		// 用右子树的最小节点替换当前节点
+		var successor *avlNode[K, V]
+		n.right, successor = n.right.removeMin()
+		successor.left, successor.right = n.left, n.right
+		return successor.balance(), true
+	}
+	return n.balance(), removed
+}
+
+// Min 返回最小的键值对
+func (m *OrderedMap[K, V]) Min() (K, V, bool) {
+	n := m.root
+	if n == nil {
+		return nodeResult[K, V](nil)
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return nodeResult(n)
+}
+
+// Max 返回最大的键值对
+func (m *OrderedMap[K, V]) Max() (K, V, bool) {
+	n := m.root
+	if n == nil {
+		return nodeResult[K, V](nil)
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return nodeResult(n)
+}
+
+// Floor 返回小于等于 key 的最大键值对
+func (m *OrderedMap[K, V]) Floor(key K) (K, V, bool) {
+	var found *avlNode[K, V]
+	for n := m.root; n != nil; {
+		c := m.compare(key, n.key)
+		if c == 0 {
+			return nodeResult(n)
+		}
+		if c < 0 {
+			n = n.left
+		} else {
+			found = n
+			n = n.right
+		}
+	}
+	return nodeResult(found)
+}
+
+// Ceiling 返回大于等于 key 的最小键值对
+func (m *OrderedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	var found *avlNode[K, V]
+	for n := m.root; n != nil; {
+		c := m.compare(key, n.key)
+		if c == 0 {
+			return nodeResult(n)
+		}
+		if c > 0 {
+			n = n.right
+		} else {
+			found = n
+			n = n.left
+		}
+	}
+	return nodeResult(found)
+}
+
+// Rank 返回小于 key 的键的数量，即 key 在有序序列中的下标（key 不存在时为插入位置）
+func (m *OrderedMap[K, V]) Rank(key K) int {
+	rank := 0
+	for n := m.root; n != nil; {
+		c := m.compare(key, n.key)
+		if c <= 0 {
+			if c == 0 {
+				return rank + n.left.getSize()
+			}
+			n = n.left
+		} else {
+			rank += n.left.getSize() + 1
+			n = n.right
+		}
+	}
+	return rank
+}
+
+// Select 返回有序序列中下标为 i 的键值对（从 0 开始），越界时返回 false
+func (m *OrderedMap[K, V]) Select(i int) (K, V, bool) {
+	if i < 0 || i >= m.Len() {
+		return nodeResult[K, V](nil)
+	}
+	n := m.root
+	for {
+		leftSize := n.left.getSize()
+		switch {
+		case i < leftSize:
+			n = n.left
+		case i > leftSize:
+			i -= leftSize + 1
+			n = n.right
+		default:
+			return nodeResult(n)
+		}
+	}
+}
+
+// All 按键从小到大遍历所有键值对
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.walk(m.root, nil, nil, yield)
+	}
+}
+
+// Keys 按从小到大的顺序遍历所有键
+func (m *OrderedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Range 按键从小到大遍历 [from, to) 区间内的键值对
+func (m *OrderedMap[K, V]) Range(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.walk(m.root, &from, &to, yield)
+	}
+}
+
+// walk 中序遍历子树中位于 [from, to) 的节点，from/to 为 nil 表示不限制；yield 返回 false 时停止并返回 false
+func (m *OrderedMap[K, V]) walk(n *avlNode[K, V], from, to *K, yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	afterFrom := from == nil || m.compare(n.key, *from) >= 0
+	beforeTo := to == nil || m.compare(n.key, *to) < 0
+	if afterFrom && !m.walk(n.left, from, to, yield) {
+		return false
+	}
+	if afterFrom && beforeTo && !yield(n.key, n.value) {
+		return false
+	}
+	if beforeTo {
+		return m.walk(n.right, from, to, yield)
+	}
+	return true
+}
+
+// nodeResult 把节点转换为 (键, 值, 是否存在) 三元组
+func nodeResult[K, V any](n *avlNode[K, V]) (K, V, bool) {
+	if n == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+	return n.key, n.value, true
+}
+
+// OrderedSet 有序集合，实现了 Container[T]，零值不可用，请使用 NewOrderedSet 或 NewOrderedSetFunc 创建
+type OrderedSet[T any] struct {
+	m *OrderedMap[T, struct{}]
+}
+
+var _ Container[int] = (*OrderedSet[int])(nil)
+
+// NewOrderedSet 创建元素为 cmp.Ordered 类型的有序集合
+func NewOrderedSet[T cmp.Ordered](items ...T) *OrderedSet[T] {
+	s := &OrderedSet[T]{m: NewOrderedMap[T, struct{}]()}
+	for _, item := range items {
+		s.Add(item)
+	}
+	return s
+}
+
+// NewOrderedSetFunc 创建元素实现了 Comparable[T] 的有序集合
+func NewOrderedSetFunc[T Comparable[T]](items ...T) *OrderedSet[T] {
+	s := &OrderedSet[T]{m: NewOrderedMapFunc[T, struct{}]()}
+	for _, item := range items {
+		s.Add(item)
+	}
+	return s
+}
+
+func (s *OrderedSet[T]) Add(item T) {
+	s.m.Put(item, struct{}{})
+}
+
+func (s *OrderedSet[T]) Remove(item T) bool {
+	return s.m.Delete(item)
+}
+
+func (s *OrderedSet[T]) Contains(item T) bool {
+	_, ok := s.m.Get(item)
+	return ok
+}
+
+func (s *OrderedSet[T]) Len() int {
+	return s.m.Len()
+}
+
+// Floor 返回小于等于 item 的最大元素
+func (s *OrderedSet[T]) Floor(item T) (T, bool) {
+	k, _, ok := s.m.Floor(item)
+	return k, ok
+}
+
+// Ceiling 返回大于等于 item 的最小元素
+func (s *OrderedSet[T]) Ceiling(item T) (T, bool) {
+	k, _, ok := s.m.Ceiling(item)
+	return k, ok
+}
+
+// Rank 返回小于 item 的元素数量
+func (s *OrderedSet[T]) Rank(item T) int {
+	return s.m.Rank(item)
+}
+
+// Select 返回下标为 i 的元素（从 0 开始）
+func (s *OrderedSet[T]) Select(i int) (T, bool) {
+	k, _, ok := s.m.Select(i)
+	return k, ok
+}
+
+// All 按从小到大的顺序遍历元素
+func (s *OrderedSet[T]) All() iter.Seq[T] {
+	return s.m.Keys()
+}
+
+// Range 按从小到大的顺序遍历 [from, to) 区间内的元素
+func (s *OrderedSet[T]) Range(from, to T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for k := range s.m.Range(from, to) {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
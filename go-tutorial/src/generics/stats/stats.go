@@ -0,0 +1,281 @@
+// Package stats 基于 generics.Number 约束的统计工具，int、int32、int64、float32、float64 切片可以直接使用，无需转换。
+package stats
+
+import (
+	"errors"
+	"math"
+	"slices"
+
+	"gotutorial/src/generics"
+)
+
+// ErrEmpty 对空数据求统计量时返回的错误
+var ErrEmpty = errors.New("stats: 数据为空")
+
+// 1.基础统计量
+
+// Sum 求和，结果类型与元素类型相同，整数求和可能溢出
+func Sum[T generics.Number](data []T) T {
+	var sum T
+	for _, v := range data {
+		sum += v
+	}
+	return sum
+}
+
+// Min 返回最小值
+func Min[T generics.Number](data []T) (T, error) {
+	if len(data) == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+	return slices.Min(data), nil
+}
+
+// Max 返回最大值
+func Max[T generics.Number](data []T) (T, error) {
+	if len(data) == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+	return slices.Max(data), nil
+}
+
+// Mean 返回算术平均值。按 float64 逐个累加，避免整数求和溢出
+func Mean[T generics.Number](data []T) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrEmpty
+	}
+	var sum float64
+	for _, v := range data {
+		sum += float64(v)
+	}
+	return sum / float64(len(data)), nil
+}
+
+// 2.离散程度
+
+// Variance 返回总体方差（除以 n）
+func Variance[T generics.Number](data []T) (float64, error) {
+	var acc Accumulator[T]
+	acc.AddAll(data...)
+	if acc.Count() == 0 {
+		return 0, ErrEmpty
+	}
+	return acc.Variance(), nil
+}
+
+// SampleVariance 返回样本方差（除以 n-1），至少需要两个数据
+func SampleVariance[T generics.Number](data []T) (float64, error) {
+	if len(data) < 2 {
+		return 0, errors.New("stats: 样本方差至少需要两个数据")
+	}
+	var acc Accumulator[T]
+	acc.AddAll(data...)
+	return acc.SampleVariance(), nil
+}
+
+// StdDev 返回总体标准差
+func StdDev[T generics.Number](data []T) (float64, error) {
+	variance, err := Variance(data)
+	return math.Sqrt(variance), err
+}
+
+// 3.分位数
+
+// Median 返回中位数，偶数个数据时取中间两个数的平均值
+func Median[T generics.Number](data []T) (float64, error) {
+	return Percentile(data, 50)
+}
+
+// Percentile 返回第 p 百分位数（0 <= p <= 100），在相邻两个数据之间线性插值。不会修改 data
+func Percentile[T generics.Number](data []T, p float64) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrEmpty
+	}
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, errors.New("stats: 百分位必须在 0 到 100 之间")
+	}
+	sorted := slices.Clone(data)
+	slices.Sort(sorted)
+	return percentileSorted(sorted, p), nil
+}
+
+// Percentiles 一次计算多个百分位数，只排序一次
+func Percentiles[T generics.Number](data []T, ps ...float64) ([]float64, error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	sorted := slices.Clone(data)
+	slices.Sort(sorted)
+	result := make([]float64, len(ps))
+	for i, p := range ps {
+		if p < 0 || p > 100 || math.IsNaN(p) {
+			return nil, errors.New("stats: 百分位必须在 0 到 100 之间")
+		}
+		result[i] = percentileSorted(sorted, p)
+	}
+	return result, nil
+}
+
+func percentileSorted[T generics.Number](sorted []T, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return float64(sorted[lower])*(1-weight) + float64(sorted[upper])*weight
+}
+
+// 4.直方图
+
+// Histogram 按给定的桶上界统计数据分布。第 i 个桶统计 bounds[i-1] < x <= bounds[i] 的数据，
+// 最后还有一个溢出桶统计大于最大上界的数据，因此 Counts 的长度比 bounds 多 1
+type Histogram[T generics.Number] struct {
+	bounds []T
+	counts []uint64
+	acc    Accumulator[T]
+}
+
+// NewHistogram 创建直方图，bounds 为各个桶的上界，会被排序并去重
+func NewHistogram[T generics.Number](bounds ...T) *Histogram[T] {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	return &Histogram[T]{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// LinearBuckets 生成 count 个从 start 开始、间隔为 width 的桶上界
+func LinearBuckets[T generics.Number](start, width T, count int) []T {
+	bounds := make([]T, count)
+	for i := range bounds {
+		bounds[i] = start + T(i)*width
+	}
+	return bounds
+}
+
+// ExponentialBuckets 生成 count 个从 start 开始、每次乘以 factor 的桶上界
+func ExponentialBuckets[T generics.Number](start T, factor float64, count int) []T {
+	bounds := make([]T, count)
+	current := float64(start)
+	for i := range bounds {
+		bounds[i] = T(current)
+		current *= factor
+	}
+	return bounds
+}
+
+// Observe 记录一个或多个数据
+func (h *Histogram[T]) Observe(values ...T) {
+	for _, v := range values {
+		i, _ := slices.BinarySearch(h.bounds, v)
+		h.counts[i]++
+		h.acc.Add(v)
+	}
+}
+
+// Bounds 返回桶上界
+func (h *Histogram[T]) Bounds() []T {
+	return slices.Clone(h.bounds)
+}
+
+// Counts 返回每个桶的数据数量，最后一个元素是溢出桶
+func (h *Histogram[T]) Counts() []uint64 {
+	return slices.Clone(h.counts)
+}
+
+// Cumulative 返回小于等于各个上界的累计数量（与 Prometheus 直方图的 le 语义相同），最后一个元素为总数
+func (h *Histogram[T]) Cumulative() []uint64 {
+	cumulative := make([]uint64, len(h.counts))
+	var total uint64
+	for i, c := range h.counts {
+		total += c
+		cumulative[i] = total
+	}
+	return cumulative
+}
+
+// Summary 返回所有已记录数据的流式统计量
+func (h *Histogram[T]) Summary() Accumulator[T] {
+	return h.acc
+}
+
+// 5.流式统计
+
+// Accumulator 使用 Welford 算法在线计算均值和方差，不需要保存原始数据，数值稳定。零值可以直接使用
+type Accumulator[T generics.Number] struct {
+	count    int
+	sum      T
+	min, max T
+	mean     float64
+	m2       float64 // 与均值之差的平方和
+}
+
+// Add 加入一个数据
+func (a *Accumulator[T]) Add(v T) {
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.count++
+	a.sum += v
+	delta := float64(v) - a.mean
+	a.mean += delta / float64(a.count)
+	a.m2 += delta * (float64(v) - a.mean)
+}
+
+// AddAll 依次加入多个数据
+func (a *Accumulator[T]) AddAll(values ...T) {
+	for _, v := range values {
+		a.Add(v)
+	}
+}
+
+// Merge 合并另一个累加器的数据（Chan 等人的并行算法），可用于分片统计后汇总
+func (a *Accumulator[T]) Merge(other Accumulator[T]) {
+	if other.count == 0 {
+		return
+	}
+	if a.count == 0 {
+		*a = other
+		return
+	}
+	count := a.count + other.count
+	delta := other.mean - a.mean
+	a.m2 += other.m2 + delta*delta*float64(a.count)*float64(other.count)/float64(count)
+	a.mean += delta * float64(other.count) / float64(count)
+	a.sum += other.sum
+	a.min = min(a.min, other.min)
+	a.max = max(a.max, other.max)
+	a.count = count
+}
+
+func (a *Accumulator[T]) Count() int { return a.count }
+func (a *Accumulator[T]) Sum() T     { return a.sum }
+func (a *Accumulator[T]) Min() T     { return a.min }
+func (a *Accumulator[T]) Max() T     { return a.max }
+func (a *Accumulator[T]) Mean() float64 {
+	return a.mean
+}
+
+// Variance 返回总体方差，没有数据时为 0
+func (a *Accumulator[T]) Variance() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	return a.m2 / float64(a.count)
+}
+
+// SampleVariance 返回样本方差，少于两个数据时为 0
+func (a *Accumulator[T]) SampleVariance() float64 {
+	if a.count < 2 {
+		return 0
+	}
+	return a.m2 / float64(a.count-1)
+}
+
+// StdDev 返回总体标准差
+func (a *Accumulator[T]) StdDev() float64 {
+	return math.Sqrt(a.Variance())
+}
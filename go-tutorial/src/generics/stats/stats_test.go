@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+// almostEqual 比较浮点数，允许微小误差
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestBasic 测试求和、最值和平均值
+func TestBasic(t *testing.T) {
+	ints := []int{3, 1, 4, 1, 5, 9, 2, 6}
+	if sum := Sum(ints); sum != 31 {
+		t.Errorf("Sum = %d, 期望 31", sum)
+	}
+	if v, err := Min(ints); err != nil || v != 1 {
+		t.Errorf("Min = %d, %v, 期望 1", v, err)
+	}
+	if v, err := Max(ints); err != nil || v != 9 {
+		t.Errorf("Max = %d, %v, 期望 9", v, err)
+	}
+	if mean, err := Mean(ints); err != nil || !almostEqual(mean, 3.875) {
+		t.Errorf("Mean = %v, %v, 期望 3.875", mean, err)
+	}
+
+	floats := []float32{1.5, 2.5}
+	if sum := Sum(floats); sum != 4 {
+		t.Errorf("Sum(float32) = %v, 期望 4", sum)
+	}
+
+	if _, err := Min([]int64{}); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Min(空) 期望 ErrEmpty, 实际 %v", err)
+	}
+	if _, err := Mean([]float64(nil)); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Mean(空) 期望 ErrEmpty, 实际 %v", err)
+	}
+}
+
+// TestDispersion 测试方差和标准差
+func TestDispersion(t *testing.T) {
+	data := []int{2, 4, 4, 4, 5, 5, 7, 9}
+	if v, _ := Variance(data); !almostEqual(v, 4) {
+		t.Errorf("Variance = %v, 期望 4", v)
+	}
+	if v, _ := StdDev(data); !almostEqual(v, 2) {
+		t.Errorf("StdDev = %v, 期望 2", v)
+	}
+	if v, _ := SampleVariance(data); !almostEqual(v, 32.0/7) {
+		t.Errorf("SampleVariance = %v, 期望 %v", v, 32.0/7)
+	}
+	if _, err := SampleVariance([]int{1}); err == nil {
+		t.Error("SampleVariance 单个数据期望返回错误")
+	}
+}
+
+// TestPercentile 测试中位数和百分位数
+func TestPercentile(t *testing.T) {
+	data := []int{7, 1, 3, 5}
+	original := slices.Clone(data)
+	if m, _ := Median(data); !almostEqual(m, 4) {
+		t.Errorf("Median = %v, 期望 4", m)
+	}
+	if !slices.Equal(data, original) {
+		t.Errorf("Percentile 不应修改输入, 实际 %v", data)
+	}
+	if m, _ := Median([]float64{3, 1, 2}); !almostEqual(m, 2) {
+		t.Errorf("Median(奇数个) = %v, 期望 2", m)
+	}
+
+	ps, err := Percentiles(data, 0, 25, 100)
+	if err != nil || !almostEqual(ps[0], 1) || !almostEqual(ps[1], 2.5) || !almostEqual(ps[2], 7) {
+		t.Errorf("Percentiles = %v, %v, 期望 [1 2.5 7]", ps, err)
+	}
+	if _, err := Percentile(data, 101); err == nil {
+		t.Error("Percentile(101) 期望返回错误")
+	}
+}
+
+// TestHistogram 测试直方图分桶
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(10, 5, 20, 5)
+	if bounds := h.Bounds(); !slices.Equal(bounds, []int{5, 10, 20}) {
+		t.Errorf("Bounds = %v, 期望 [5 10 20]", bounds)
+	}
+	h.Observe(1, 5, 6, 10, 15, 20, 21, 100)
+	if counts := h.Counts(); !slices.Equal(counts, []uint64{2, 2, 2, 2}) {
+		t.Errorf("Counts = %v, 期望 [2 2 2 2]", counts)
+	}
+	if cumulative := h.Cumulative(); !slices.Equal(cumulative, []uint64{2, 4, 6, 8}) {
+		t.Errorf("Cumulative = %v, 期望 [2 4 6 8]", cumulative)
+	}
+	summary := h.Summary()
+	if summary.Count() != 8 || summary.Sum() != 178 || summary.Max() != 100 {
+		t.Errorf("Summary 期望 8 个数据、总和 178、最大 100, 实际 %d, %d, %d", summary.Count(), summary.Sum(), summary.Max())
+	}
+
+	if b := LinearBuckets(0.5, 0.5, 3); !slices.Equal(b, []float64{0.5, 1, 1.5}) {
+		t.Errorf("LinearBuckets = %v, 期望 [0.5 1 1.5]", b)
+	}
+	if b := ExponentialBuckets[int64](1, 10, 4); !slices.Equal(b, []int64{1, 10, 100, 1000}) {
+		t.Errorf("ExponentialBuckets = %v, 期望 [1 10 100 1000]", b)
+	}
+}
+
+// TestAccumulator 测试 Welford 流式统计及合并
+func TestAccumulator(t *testing.T) {
+	data := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	var acc Accumulator[float64]
+	acc.AddAll(data...)
+	if acc.Count() != 8 || !almostEqual(acc.Mean(), 5) || !almostEqual(acc.Variance(), 4) {
+		t.Errorf("Accumulator 期望 8 个数据、均值 5、方差 4, 实际 %d, %v, %v", acc.Count(), acc.Mean(), acc.Variance())
+	}
+	if acc.Min() != 2 || acc.Max() != 9 {
+		t.Errorf("Accumulator 最值 = %v, %v, 期望 2, 9", acc.Min(), acc.Max())
+	}
+
+	// 分两段统计后合并，结果应与整体统计一致
+	var left, right Accumulator[float64]
+	left.AddAll(data[:3]...)
+	right.AddAll(data[3:]...)
+	left.Merge(right)
+	if left.Count() != acc.Count() || !almostEqual(left.Mean(), acc.Mean()) || !almostEqual(left.Variance(), acc.Variance()) ||
+		left.Min() != acc.Min() || left.Max() != acc.Max() {
+		t.Errorf("Merge 结果与整体统计不一致: %+v, %+v", left, acc)
+	}
+
+	// 大偏移量的数据，朴素公式会因相减抵消丢失精度
+	var shifted Accumulator[int64]
+	for _, v := range []int64{4, 7, 13, 16} {
+		shifted.Add(1_000_000_000 + v)
+	}
+	if !almostEqual(shifted.SampleVariance(), 30) {
+		t.Errorf("SampleVariance = %v, 期望 30", shifted.SampleVariance())
+	}
+
+	var empty Accumulator[int]
+	if empty.Variance() != 0 || empty.StdDev() != 0 {
+		t.Error("空累加器的方差期望为 0")
+	}
+}
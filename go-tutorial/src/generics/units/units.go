@@ -0,0 +1,261 @@
+// Package units 基于 generics.Addable 约束的度量单位。每种物理量都是独立的自定义类型，
+// 同类量可以直接相加减，不同类的量混用（例如 Meter + Kilogram）无法通过编译。
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotutorial/src/generics"
+)
+
+// 1.物理量类型
+
+// Length 长度，以米为基本单位
+type Length float64
+
+const (
+	Millimeter Length = 0.001
+	Centimeter Length = 0.01
+	Meter      Length = 1
+	Kilometer  Length = 1000
+	Inch       Length = 0.0254
+	Foot       Length = 0.3048
+	Mile       Length = 1609.344
+)
+
+// Mass 质量，以千克为基本单位
+type Mass float64
+
+const (
+	Gram     Mass = 0.001
+	Kilogram Mass = 1
+	Tonne    Mass = 1000
+	Ounce    Mass = 0.028349523125
+	Pound    Mass = 0.45359237
+)
+
+// DataSize 数据量，以字节为基本单位。底层类型为 int，64 位平台上最大约 8EiB
+type DataSize int
+
+const (
+	Byte DataSize = 1
+
+	Kilobyte DataSize = 1000 * Byte
+	Megabyte DataSize = 1000 * Kilobyte
+	Gigabyte DataSize = 1000 * Megabyte
+	Terabyte DataSize = 1000 * Gigabyte
+
+	Kibibyte DataSize = 1024 * Byte
+	Mebibyte DataSize = 1024 * Kibibyte
+	Gibibyte DataSize = 1024 * Mebibyte
+	Tebibyte DataSize = 1024 * Gibibyte
+)
+
+// Speed 速度，以米每秒为基本单位
+type Speed float64
+
+const (
+	MeterPerSecond   Speed = 1
+	KilometerPerHour Speed = 1000.0 / 3600
+	MilePerHour      Speed = 1609.344 / 3600
+)
+
+// DataRate 数据传输速率，以字节每秒为基本单位
+type DataRate float64
+
+const (
+	BytePerSecond     DataRate = 1
+	KibibytePerSecond DataRate = 1 << 10
+	MebibytePerSecond DataRate = 1 << 20
+	GibibytePerSecond DataRate = 1 << 30
+	MegabitPerSecond  DataRate = 1e6 / 8
+)
+
+// 2.通用运算：只要求底层类型是数字，对所有物理量都适用，并且结果保持原来的类型
+
+// Sum 求同一种物理量的总和
+func Sum[T generics.Addable](values ...T) T {
+	var total T
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Scale 把物理量乘以一个无量纲系数，整数类型的结果四舍五入
+func Scale[T generics.Addable](v T, factor float64) T {
+	return fromFloat[T](float64(v) * factor)
+}
+
+// fromFloat 把浮点数转换为 T，T 为整数类型时四舍五入而不是截断
+func fromFloat[T generics.Addable](f float64) T {
+	half := 0.5
+	if T(half) == 0 {
+		f = math.Round(f)
+	}
+	return T(f)
+}
+
+// inRange 判断 f 转换为 T 时是否不会溢出。T 为整数类型时超出范围的转换不会报错，而是得到错误的值；
+// T 为浮点类型时溢出得到的是 ±Inf，同样视为超出范围
+func inRange[T generics.Addable](f float64) bool {
+	half := 0.5
+	if T(half) != 0 {
+		return !math.IsInf(f, 0) && !math.IsNaN(f)
+	}
+	f = math.Round(f)
+	return f >= math.MinInt && f < -math.MinInt // -math.MinInt 即 MaxInt+1，能被 float64 精确表示
+}
+
+// Convert 把物理量换算成以 unit 为单位的数值，例如 Convert(3*Kilometer, Mile)
+func Convert[T generics.Addable](v, unit T) float64 {
+	return float64(v) / float64(unit)
+}
+
+func (l Length) In(unit Length) float64     { return Convert(l, unit) }
+func (m Mass) In(unit Mass) float64         { return Convert(m, unit) }
+func (d DataSize) In(unit DataSize) float64 { return Convert(d, unit) }
+func (s Speed) In(unit Speed) float64       { return Convert(s, unit) }
+func (r DataRate) In(unit DataRate) float64 { return Convert(r, unit) }
+
+// 3.速率：长度、数据量与时间之间的换算
+
+// SpeedOf 返回在 d 时间内移动 l 的速度。d 为 0 时按浮点数除法得到 ±Inf（l 也为 0 时为 NaN）
+func SpeedOf(l Length, d time.Duration) Speed {
+	return Speed(float64(l) / d.Seconds())
+}
+
+// Over 返回以该速度移动 d 时间的距离
+func (s Speed) Over(d time.Duration) Length {
+	return Length(float64(s) * d.Seconds())
+}
+
+// RateOf 返回在 d 时间内传输 size 的速率。d 为 0 时与 SpeedOf 一样得到 ±Inf 或 NaN
+func RateOf(size DataSize, d time.Duration) DataRate {
+	return DataRate(float64(size) / d.Seconds())
+}
+
+// Over 返回以该速率传输 d 时间的数据量
+func (r DataRate) Over(d time.Duration) DataSize {
+	return DataSize(math.Round(float64(r) * d.Seconds()))
+}
+
+// TimeFor 返回以该速率传输 size 需要的时间。size 为 0 时返回 0；
+// 速率为 0（永远传不完）或结果超出 Duration 的范围时返回 Duration 能表示的最大（size 为负数时为最小）值
+func (r DataRate) TimeFor(size DataSize) time.Duration {
+	if size == 0 {
+		return 0
+	}
+	// Inf 或超出范围的浮点数直接转换为 Duration 得到的是错误的值
+	d := float64(size) / float64(r) * float64(time.Second)
+	switch {
+	case d >= math.MaxInt64:
+		return math.MaxInt64
+	case d <= math.MinInt64:
+		return math.MinInt64
+	}
+	return time.Duration(d)
+}
+
+// 4.解析与格式化
+
+// symbol 单位符号及其对应的量
+type symbol[T generics.Addable] struct {
+	name  string
+	value T
+}
+
+// 各物理量可以解析的单位符号。前面的若干个（见下方的 xxxDisplay）用于格式化，按从大到小排列
+var (
+	lengthSymbols = []symbol[Length]{
+		{"km", Kilometer}, {"m", Meter}, {"cm", Centimeter}, {"mm", Millimeter},
+		{"mi", Mile}, {"ft", Foot}, {"in", Inch},
+	}
+	massSymbols = []symbol[Mass]{
+		{"t", Tonne}, {"kg", Kilogram}, {"g", Gram}, {"lb", Pound}, {"oz", Ounce},
+	}
+	dataSizeSymbols = []symbol[DataSize]{
+		{"TiB", Tebibyte}, {"GiB", Gibibyte}, {"MiB", Mebibyte}, {"KiB", Kibibyte}, {"B", Byte},
+		{"TB", Terabyte}, {"GB", Gigabyte}, {"MB", Megabyte}, {"KB", Kilobyte}, {"kB", Kilobyte},
+	}
+	speedSymbols = []symbol[Speed]{
+		{"m/s", MeterPerSecond}, {"km/h", KilometerPerHour}, {"mph", MilePerHour},
+	}
+	dataRateSymbols = []symbol[DataRate]{
+		{"GiB/s", GibibytePerSecond}, {"MiB/s", MebibytePerSecond}, {"KiB/s", KibibytePerSecond}, {"B/s", BytePerSecond},
+		{"Mbps", MegabitPerSecond},
+	}
+)
+
+// 格式化时依次尝试的单位数量（symbols 的前 n 个）
+const (
+	lengthDisplay   = 4
+	massDisplay     = 3
+	dataSizeDisplay = 5
+	speedDisplay    = 1
+	dataRateDisplay = 4
+)
+
+// parse 解析 "数值+单位" 形式的字符串，数值与单位之间可以有空格，单位区分大小写
+func parse[T generics.Addable](kind, s string, symbols []symbol[T]) (T, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+' || r == 'e' || r == 'E')
+	})
+	if i <= 0 {
+		return 0, fmt.Errorf("units: 无法解析%s %q：缺少数值或单位", kind, s)
+	}
+	number, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("units: 无法解析%s %q：%w", kind, s, err)
+	}
+	name := strings.TrimSpace(s[i:])
+	for _, sym := range symbols {
+		if sym.name == name {
+			f := number * float64(sym.value)
+			if !inRange[T](f) {
+				return 0, fmt.Errorf("units: 无法解析%s %q：%w", kind, s, strconv.ErrRange)
+			}
+			return fromFloat[T](f), nil
+		}
+	}
+	return 0, fmt.Errorf("units: 无法解析%s %q：未知单位 %q", kind, s, name)
+}
+
+// format 选择使数值的绝对值不小于 1 的最大单位，保留至多两位小数；零值使用基本单位
+func format[T generics.Addable](v T, symbols []symbol[T]) string {
+	unit := symbols[len(symbols)-1]
+	for _, sym := range symbols {
+		if v == 0 && sym.value == 1 || v != 0 && math.Abs(float64(v)) >= float64(sym.value) {
+			unit = sym
+			break
+		}
+	}
+	number := math.Round(Convert(v, unit.value)*100) / 100
+	return strconv.FormatFloat(number, 'f', -1, 64) + unit.name
+}
+
+// ParseLength 解析长度，例如 "1.5km"、"6 ft"
+func ParseLength(s string) (Length, error) { return parse("长度", s, lengthSymbols) }
+
+// ParseMass 解析质量，例如 "70kg"、"2.5lb"
+func ParseMass(s string) (Mass, error) { return parse("质量", s, massSymbols) }
+
+// ParseDataSize 解析数据量，支持二进制单位（KiB、MiB…）和十进制单位（KB、MB…），例如 "1.5GiB"
+func ParseDataSize(s string) (DataSize, error) { return parse("数据量", s, dataSizeSymbols) }
+
+// ParseSpeed 解析速度，例如 "120km/h"
+func ParseSpeed(s string) (Speed, error) { return parse("速度", s, speedSymbols) }
+
+// ParseDataRate 解析数据传输速率，例如 "100Mbps"、"20MiB/s"
+func ParseDataRate(s string) (DataRate, error) { return parse("速率", s, dataRateSymbols) }
+
+func (l Length) String() string   { return format(l, lengthSymbols[:lengthDisplay]) }
+func (m Mass) String() string     { return format(m, massSymbols[:massDisplay]) }
+func (d DataSize) String() string { return format(d, dataSizeSymbols[:dataSizeDisplay]) }
+func (s Speed) String() string    { return format(s, speedSymbols[:speedDisplay]) }
+func (r DataRate) String() string { return format(r, dataRateSymbols[:dataRateDisplay]) }
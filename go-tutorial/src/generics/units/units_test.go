@@ -0,0 +1,152 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestArithmetic 测试同类物理量运算与单位换算
+func TestArithmetic(t *testing.T) {
+	// 同类量可以直接相加；Meter + Kilogram 无法通过编译
+	total := Sum(1*Kilometer, 500*Meter, 250*Meter)
+	if total != 1750*Meter {
+		t.Errorf("Sum = %v, 期望 1.75km", total)
+	}
+	if miles := (5 * Kilometer).In(Mile); math.Abs(miles-3.10686) > 1e-5 {
+		t.Errorf("5km = %v mi, 期望约 3.10686", miles)
+	}
+	if kg := (10 * Pound).In(Kilogram); !almostEqual(kg, 4.5359237) {
+		t.Errorf("10lb = %v kg, 期望 4.5359237", kg)
+	}
+	if got := Scale(3*Byte, 0.5); got != 2 {
+		t.Errorf("Scale(3B, 0.5) = %d, 期望四舍五入为 2", got)
+	}
+	if got := Scale(2*Kilogram, 0.25); got != 500*Gram {
+		t.Errorf("Scale(2kg, 0.25) = %v, 期望 500g", got)
+	}
+}
+
+// TestRates 测试速率与时间的换算
+func TestRates(t *testing.T) {
+	speed := SpeedOf(100*Kilometer, 2*time.Hour)
+	if !almostEqual(speed.In(KilometerPerHour), 50) {
+		t.Errorf("SpeedOf = %v km/h, 期望 50", speed.In(KilometerPerHour))
+	}
+	if got := speed.Over(30 * time.Minute); !almostEqual(float64(got), float64(25*Kilometer)) {
+		t.Errorf("Over = %v, 期望 25km", got)
+	}
+
+	rate := RateOf(10*Mebibyte, 2*time.Second)
+	if rate != 5*MebibytePerSecond {
+		t.Errorf("RateOf = %v, 期望 5MiB/s", rate)
+	}
+	if got := rate.Over(time.Second); got != 5*Mebibyte {
+		t.Errorf("Over = %v, 期望 5MiB", got)
+	}
+	if got := rate.TimeFor(Gibibyte); got != 204800*time.Millisecond {
+		t.Errorf("TimeFor(1GiB) = %v, 期望 3m24.8s", got)
+	}
+
+	// 除数为 0
+	if speed := SpeedOf(Kilometer, 0); !math.IsInf(float64(speed), 1) {
+		t.Errorf("SpeedOf(1km, 0) = %v, 期望 +Inf", float64(speed))
+	}
+	if rate := RateOf(0, 0); !math.IsNaN(float64(rate)) {
+		t.Errorf("RateOf(0, 0) = %v, 期望 NaN", float64(rate))
+	}
+	for _, tc := range []struct {
+		rate DataRate
+		size DataSize
+		want time.Duration
+	}{
+		{0, Gibibyte, math.MaxInt64},
+		{0, -Gibibyte, math.MinInt64},
+		{0, 0, 0},
+		{BytePerSecond, 1 << 62, math.MaxInt64},
+	} {
+		if got := tc.rate.TimeFor(tc.size); got != tc.want {
+			t.Errorf("DataRate(%v).TimeFor(%d) = %v, 期望 %v", float64(tc.rate), tc.size, got, tc.want)
+		}
+	}
+}
+
+// TestParse 测试从字符串解析
+func TestParse(t *testing.T) {
+	size, err := ParseDataSize("1.5GiB")
+	if err != nil || size != Gibibyte+512*Mebibyte {
+		t.Errorf("ParseDataSize(1.5GiB) = %d, %v", size, err)
+	}
+	if size, _ := ParseDataSize("2 MB"); size != 2_000_000 {
+		t.Errorf("ParseDataSize(2 MB) = %d, 期望 2000000", size)
+	}
+	if length, _ := ParseLength("6ft"); !almostEqual(float64(length), 1.8288) {
+		t.Errorf("ParseLength(6ft) = %v, 期望 1.8288m", float64(length))
+	}
+	if mass, _ := ParseMass("-2.5kg"); mass != -2.5 {
+		t.Errorf("ParseMass(-2.5kg) = %v, 期望 -2.5kg", mass)
+	}
+	if speed, _ := ParseSpeed("36km/h"); !almostEqual(float64(speed), 10) {
+		t.Errorf("ParseSpeed(36km/h) = %v, 期望 10m/s", speed)
+	}
+	if rate, _ := ParseDataRate("100Mbps"); rate != 12_500_000 {
+		t.Errorf("ParseDataRate(100Mbps) = %v, 期望 12500000B/s", float64(rate))
+	}
+
+	for _, s := range []string{"", "GiB", "12", "1.5XB", "1..5m"} {
+		if _, err := ParseDataSize(s); err == nil {
+			t.Errorf("ParseDataSize(%q) 期望返回错误", s)
+		}
+	}
+	// 超出 int 范围时返回错误，而不是溢出成错误的值
+	for _, s := range []string{"99999999TiB", "-99999999TiB", "1e19B"} {
+		if size, err := ParseDataSize(s); !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("ParseDataSize(%q) = %v, %v; 期望 strconv.ErrRange", s, size, err)
+		}
+	}
+	if size, err := ParseDataSize("8191TiB"); err != nil || size != 8191*Tebibyte {
+		t.Errorf("ParseDataSize(8191TiB) = %v, %v; 期望 8191TiB", size, err)
+	}
+	// 浮点类型的物理量溢出成 ±Inf 时同样返回错误
+	if length, err := ParseLength("1e308km"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("ParseLength(1e308km) = %v, %v; 期望 strconv.ErrRange", float64(length), err)
+	}
+	if rate, err := ParseDataRate("-1e308GiB/s"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("ParseDataRate(-1e308GiB/s) = %v, %v; 期望 strconv.ErrRange", float64(rate), err)
+	}
+}
+
+// TestFormat 测试可读格式化
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{(1536 * Mebibyte).String(), "1.5GiB"},
+		{(999 * Byte).String(), "999B"},
+		{DataSize(0).String(), "0B"},
+		{(1234 * Meter).String(), "1.23km"},
+		{(25 * Centimeter).String(), "25cm"},
+		{Length(0).String(), "0m"},
+		{(1500 * Gram).String(), "1.5kg"},
+		{(-2 * Tonne).String(), "-2t"},
+		{(3 * MebibytePerSecond).String(), "3MiB/s"},
+		{(72 * KilometerPerHour).String(), "20m/s"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("String() = %s, 期望 %s", tt.got, tt.want)
+		}
+	}
+
+	// 格式化结果可以重新解析
+	size := 3*Gibibyte + 256*Mebibyte
+	if parsed, err := ParseDataSize(size.String()); err != nil || parsed != size {
+		t.Errorf("ParseDataSize(%s) = %d, %v, 期望 %d", size, parsed, err, size)
+	}
+}
@@ -0,0 +1,415 @@
+package generics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Codec 持久化模式下元素的编解码器
+type Codec[T any] interface {
+	Encode(item T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// JSONCodec 使用 encoding/json 编解码元素
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(item T) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var item T
+	err := json.Unmarshal(data, &item)
+	return item, err
+}
+
+// 预写日志的记录格式为：记录长度(uvarint) + 记录内容 + 记录内容的 CRC32(IEEE，小端 4 字节)。
+// 记录内容为：类型(1 字节) + id(uvarint)，推送记录之后还有：
+// 优先级(varint) + 最早投递时间(varint，Unix 纳秒，0 表示立即投递) + 数据长度(uvarint) + 数据
+const (
+	walPush byte = 'P' // 元素进入队列
+	walAck  byte = 'A' // 元素处理完成（成功、进入死信或被丢弃）
+)
+
+// walCompactThreshold 累计确认多少条记录后重写日志，去掉已经确认的记录
+const walCompactThreshold = 1024
+
+// wal 预写日志：推送的元素先追加到日志文件再进入队列，处理完成后追加确认记录。
+// 进程重启后，日志中没有确认记录的元素会被重新投递，因此持久化模式提供的是至少一次（at-least-once）语义。
+type wal[T any] struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	codec    Codec[T]
+	nextID   uint64
+	pending  map[uint64]walRecord[T] // 尚未确认的元素
+	inflight map[uint64]struct{}     // 已写入日志、但推送它的 Push 还没有把它放进队列的元素
+	acked    int                     // 上次压缩之后确认的记录数
+}
+
+// walRecord 尚未确认的元素及其编码数据，编码数据用于压缩时重写日志
+type walRecord[T any] struct {
+	item T
+	pushOptions
+	data []byte
+}
+
+// openWAL 打开（不存在时创建）日志文件并读取、解码其中尚未确认的元素。
+// 从第一条不完整或校验失败的记录（崩溃时写了一半，或者文件尾部损坏）开始的内容会被截掉。
+func openWAL[T any](path string, codec Codec[T]) (*wal[T], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := &wal[T]{
+		path:     path,
+		file:     file,
+		codec:    codec,
+		nextID:   1,
+		pending:  make(map[uint64]walRecord[T]),
+		inflight: make(map[uint64]struct{}),
+	}
+	valid, err := w.load()
+	if err == nil {
+		err = w.decode()
+	}
+	if err == nil {
+		err = file.Truncate(valid)
+	}
+	if err == nil {
+		_, err = file.Seek(valid, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// load 重放日志，返回最后一条有效记录的结束位置
+func (w *wal[T]) load() (int64, error) {
+	info, err := w.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	reader := &countingReader{r: bufio.NewReader(w.file)}
+	var valid int64
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return valid, nil
+		}
+		if err != nil || size > uint64(info.Size()-reader.n) {
+			return valid, nil
+		}
+		frame := make([]byte, size+crc32.Size)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return valid, nil
+		}
+		body, sum := frame[:size], frame[size:]
+		if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(sum) {
+			return valid, nil
+		}
+		kind, id, record, ok := parseRecord[T](body)
+		if !ok {
+			return valid, nil
+		}
+		switch kind {
+		case walPush:
+			w.pending[id] = record
+		case walAck:
+			delete(w.pending, id)
+		}
+		w.nextID = max(w.nextID, id+1)
+		valid = reader.n
+	}
+}
+
+// parseRecord 解析一条记录的内容，内容不合法时 ok 为 false
+func parseRecord[T any](body []byte) (kind byte, id uint64, record walRecord[T], ok bool) {
+	reader := bytes.NewReader(body)
+	kind, err := reader.ReadByte()
+	if err != nil {
+		return
+	}
+	if id, err = binary.ReadUvarint(reader); err != nil {
+		return
+	}
+	switch kind {
+	case walAck:
+		return kind, id, record, reader.Len() == 0
+	case walPush:
+		priority, err := binary.ReadVarint(reader)
+		if err != nil {
+			return
+		}
+		notBefore, err := binary.ReadVarint(reader)
+		if err != nil {
+			return
+		}
+		size, err := binary.ReadUvarint(reader)
+		if err != nil || size != uint64(reader.Len()) {
+			return
+		}
+		record.priority = int(priority)
+		if notBefore != 0 {
+			record.notBefore = time.Unix(0, notBefore)
+		}
+		record.data = body[len(body)-int(size):]
+		return kind, id, record, true
+	}
+	return
+}
+
+// decode 解码重放得到的元素
+func (w *wal[T]) decode() error {
+	for id, record := range w.pending {
+		item, err := w.codec.Decode(record.data)
+		if err != nil {
+			return fmt.Errorf("解码预写日志记录 %d: %w", id, err)
+		}
+		record.item = item
+		w.pending[id] = record
+	}
+	return nil
+}
+
+// append 编码元素并追加推送记录，返回分配的 id。记录在调用 settle 之前处于 inflight 状态，不会被 replay 返回
+func (w *wal[T]) append(item T, o pushOptions) (uint64, error) {
+	data, err := w.codec.Encode(item)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, errWALClosed
+	}
+	id := w.nextID
+	record := walRecord[T]{item: item, pushOptions: o, data: data}
+	if err := w.write(id, &record); err != nil {
+		return 0, err
+	}
+	w.nextID++
+	w.pending[id] = record
+	w.inflight[id] = struct{}{}
+	return id, nil
+}
+
+// settle 推送记录对应的 Push 已经结束（元素进入了队列或被确认），之后重启处理器时 replay 会正常返回它
+func (w *wal[T]) settle(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.inflight, id)
+}
+
+// ack 追加确认记录，确认的记录足够多时压缩日志
+func (w *wal[T]) ack(id uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return errWALClosed
+	}
+	if _, ok := w.pending[id]; !ok {
+		return nil
+	}
+	if err := w.write(id, nil); err != nil {
+		return err
+	}
+	delete(w.pending, id)
+	w.acked++
+	if len(w.pending) == 0 || w.acked >= walCompactThreshold {
+		return w.compact()
+	}
+	return nil
+}
+
+// write 写入一条记录并同步到磁盘，record 为 nil 时写入确认记录，调用方需持有 mu
+func (w *wal[T]) write(id uint64, record *walRecord[T]) error {
+	if _, err := w.file.Write(appendRecord(nil, id, record)); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// appendRecord 把一条记录编码后追加到 buf，record 为 nil 时编码确认记录
+func appendRecord[T any](buf []byte, id uint64, record *walRecord[T]) []byte {
+	var body []byte
+	if record == nil {
+		body = binary.AppendUvarint([]byte{walAck}, id)
+	} else {
+		var notBefore int64
+		if !record.notBefore.IsZero() {
+			notBefore = record.notBefore.UnixNano()
+		}
+		body = make([]byte, 0, 1+4*binary.MaxVarintLen64+len(record.data))
+		body = append(body, walPush)
+		body = binary.AppendUvarint(body, id)
+		body = binary.AppendVarint(body, int64(record.priority))
+		body = binary.AppendVarint(body, notBefore)
+		body = binary.AppendUvarint(body, uint64(len(record.data)))
+		body = append(body, record.data...)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(body)))
+	buf = append(buf, body...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(body))
+}
+
+// compact 只保留尚未确认的推送记录：没有待确认元素时直接清空文件，
+// 否则先把记录全部写入临时文件、同步一次后再重命名，并同步所在目录使重命名落盘，
+// 保证任意时刻崩溃都不会丢失记录。调用方需持有 mu
+func (w *wal[T]) compact() error {
+	w.acked = 0
+	if len(w.pending) == 0 {
+		if err := w.file.Truncate(0); err != nil {
+			return err
+		}
+		_, err := w.file.Seek(0, io.SeekStart)
+		return err
+	}
+
+	tmp, err := os.Create(w.path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	var buf []byte
+	for _, id := range w.pendingIDs() {
+		record := w.pending[id]
+		buf = appendRecord(buf[:0], id, &record)
+		if _, err = writer.Write(buf); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	old := w.file
+	w.file = tmp
+	return errors.Join(old.Close(), syncDir(filepath.Dir(w.path)))
+}
+
+// syncDir 同步目录，使其中的重命名、创建等操作落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// pendingIDs 按推送顺序返回尚未确认的元素 id，调用方需持有 mu
+func (w *wal[T]) pendingIDs() []uint64 {
+	ids := make([]uint64, 0, len(w.pending))
+	for id := range w.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// replay 按推送顺序返回所有尚未确认的元素，跳过 inflight 的元素——它们会由推送它们的 Push 放进队列
+func (w *wal[T]) replay() []entry[T] {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entries := make([]entry[T], 0, len(w.pending))
+	for _, id := range w.pendingIDs() {
+		if _, ok := w.inflight[id]; ok {
+			continue
+		}
+		record := w.pending[id]
+		entries = append(entries, entry[T]{item: record.item, pushOptions: record.pushOptions, id: id})
+	}
+	return entries
+}
+
+func (w *wal[T]) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+var errWALClosed = errors.New("预写日志已关闭")
+
+// countingReader 记录已读取的字节数，用于定位最后一条有效记录
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// EnableWAL 为处理器开启持久化模式，必须在启动处理器之前调用。
+// 推送的元素会先追加到 path 指向的预写日志，处理完成（成功、进入死信或被 OverflowDropOldest 挤出）后才被确认；
+// 之后每次启动处理器时，日志中尚未确认的元素会连同优先级和投递时间按推送顺序重新进入队列（不受队列容量限制）。
+// 非批处理模式下 Stop 丢弃的元素以及 Shutdown 超时返回的元素不会被确认，下一次启动时会被重新投递。
+func (h *Handler[T]) EnableWAL(path string, codec Codec[T]) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state != stateIdle {
+		return errors.New("处理器运行中，无法开启持久化模式")
+	}
+	if h.wal != nil {
+		return errors.New("持久化模式已开启")
+	}
+	w, err := openWAL(path, codec)
+	if err != nil {
+		return err
+	}
+	h.wal = w
+	return nil
+}
+
+// CloseWAL 停止处理器并关闭预写日志，之后处理器回到非持久化模式
+func (h *Handler[T]) CloseWAL() error {
+	h.Stop()
+	h.mu.Lock()
+	w := h.wal
+	h.wal = nil
+	h.mu.Unlock()
+	if w == nil {
+		return nil
+	}
+	return w.close()
+}
@@ -0,0 +1,540 @@
+package sync
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TypedCache 并发安全的泛型键值缓存，支持过期时间（TTL）和按淘汰策略限制条目数量。
+// 过期的条目在读取时惰性删除，也可以通过 WithJanitor 启动后台清理；启动了后台清理或定期快照的缓存用完后需要调用 Close。
+//
+// 读多写少：Get 只持有读锁，多个 goroutine 可以同时读取。读取只修改条目自身的访问时间和次数，以及分条带的命中计数，
+// 不写任何所有读取共享的变量，核数增加时读取不会因为争抢同一条缓存行而变慢。
+// 不能在持有读锁时调用 Lock 升级为写锁——写锁要等所有读锁释放，而当前 goroutine 自己还持有读锁，会永远等下去。
+// 设置了最大条目数时，条目按淘汰顺序保存在堆中（见 eviction.go），淘汰的总是按策略最应被淘汰的条目；
+// LRU 的访问时间精确到时钟的精度，同一时刻访问的条目先写入的先被淘汰。
+type TypedCache[K comparable, V any] struct {
+	rwMutext sync.RWMutex
+	data     map[K]*cacheEntry[K, V]
+	queue    *evictionQueue[K, V] // 未设置最大条目数时为 nil
+	writes   uint64               // 写入新条目的次数，只在持有写锁时修改，作为条目的写入序号
+	epoch    time.Time            // 创建时间，条目的访问时间记为相对它的纳秒数
+
+	hits       stripedCounter
+	misses     stripedCounter
+	expired    atomic.Int64
+	evicted    atomic.Int64
+	loads      atomic.Int64
+	loadErrors atomic.Int64
+
+	loadMu   sync.Mutex // 保护 calls、failures 和 sweepAt，见 load.go
+	calls    map[K]*loadCall[V]
+	failures map[K]loadFailure
+	sweepAt  int // failures 增长到这个大小时清理其中过期的错误
+
+	cacheConfig
+	onEvict     atomic.Pointer[func(key K, value V, reason EvictionReason)]
+	subscribers []*Subscription[K, V] // 由 rwMutext 保护，见 events.go
+	now         func() time.Time
+
+	stop       chan struct{}  // Close 时关闭，通知后台 goroutine 退出
+	background sync.WaitGroup // 后台清理和定期快照
+	closeOnce  sync.Once
+}
+
+// Cache 键为字符串、值为任意类型的缓存，保留泛型化之前的用法，读取结果需要类型断言
+type Cache = TypedCache[string, interface{}]
+
+type CacheStats struct {
+	Hits    int
+	Misses  int
+	Expired int // 因过期被删除的条目数
+	Evicted int // 因超出最大条目数被淘汰的条目数
+
+	Loads      int // GetOrLoad 调用 loader 的次数
+	LoadErrors int // loader 返回错误的次数
+}
+
+// EvictionPolicy 超出最大条目数时选择淘汰哪个条目
+type EvictionPolicy int
+
+const (
+	EvictLRU  EvictionPolicy = iota // 淘汰最久没有访问的条目
+	EvictLFU                        // 淘汰访问次数最少的条目，次数相同时淘汰最久没有访问的
+	EvictFIFO                       // 淘汰最早写入的条目，之后的访问和更新不改变顺序
+)
+
+// EvictionReason 条目被移出缓存的原因
+type EvictionReason int
+
+const (
+	ReasonExpired  EvictionReason = iota + 1 // 过期
+	ReasonCapacity                           // 超出最大条目数
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonCapacity:
+		return "capacity"
+	}
+	return "unknown"
+}
+
+// cacheConfig 通过 ConfigOption 设置的配置，与键值类型无关，因此同一组选项可以用于任意 TypedCache
+type cacheConfig struct {
+	defaultTTL      time.Duration
+	maxEntries      int
+	policy          EvictionPolicy
+	janitorInterval time.Duration
+	errorTTL        time.Duration
+	snapshot        *snapshotConfig // 定期快照的配置，见 snapshot.go
+}
+
+// ConfigOption 与键值类型无关的可选配置，可以用于 NewCache、NewTypedCache 和 NewShardedCache
+type ConfigOption func(c *cacheConfig)
+
+func (o ConfigOption) applyCache(c *cacheOptions) {
+	o(&c.cacheConfig)
+}
+
+// CacheOption 创建 Cache 和 ShardedCache 时的可选配置：ConfigOption，或者 WithOnEvict 这种与键值类型相关的选项。
+// NewTypedCache 只接受 ConfigOption，把 WithOnEvict 用于其他键值类型的缓存时编译报错，键值类型相关的配置改用对应的方法（如 OnEvict）
+type CacheOption interface {
+	applyCache(c *cacheOptions)
+}
+
+// cacheOptions CacheOption 设置的全部配置。先把选项解析到这里再创建缓存，ShardedCache 可以调整之后用于每个分片
+type cacheOptions struct {
+	cacheConfig
+	onEvict func(key string, value interface{}, reason EvictionReason)
+}
+
+// parseCacheOptions 依次应用 opts，返回得到的配置
+func parseCacheOptions(opts []CacheOption) cacheOptions {
+	o := cacheOptions{cacheConfig: cacheConfig{errorTTL: defaultErrorTTL}}
+	for _, opt := range opts {
+		opt.applyCache(&o)
+	}
+	return o
+}
+
+// WithDefaultTTL 设置 Set 写入的条目的默认过期时间，0 表示永不过期
+func WithDefaultTTL(ttl time.Duration) ConfigOption {
+	return func(c *cacheConfig) {
+		c.defaultTTL = ttl
+	}
+}
+
+// WithMaxEntries 设置最大条目数及淘汰策略，写入新键导致超出时按策略淘汰条目，0 表示不限制
+func WithMaxEntries(n int, policy EvictionPolicy) ConfigOption {
+	return func(c *cacheConfig) {
+		c.maxEntries = n
+		c.policy = policy
+	}
+}
+
+// WithJanitor 启动后台 goroutine 每隔 interval 清理一次过期条目，需要调用 Close 停止
+func WithJanitor(interval time.Duration) ConfigOption {
+	return func(c *cacheConfig) {
+		c.janitorInterval = interval
+	}
+}
+
+// WithOnEvict 设置 Cache 的条目过期或被淘汰时的回调，与 Cache.OnEvict 相同
+func WithOnEvict(fn func(key string, value interface{}, reason EvictionReason)) CacheOption {
+	return evictCallbackOption(fn)
+}
+
+// evictCallbackOption WithOnEvict 返回的选项，只实现 CacheOption，不能用于 NewTypedCache
+type evictCallbackOption func(key string, value interface{}, reason EvictionReason)
+
+func (o evictCallbackOption) applyCache(c *cacheOptions) {
+	c.onEvict = o
+}
+
+// NewCache 创建键为字符串、值为任意类型的缓存
+func NewCache(opts ...CacheOption) *Cache {
+	return newCache(parseCacheOptions(opts))
+}
+
+// newCache 按解析好的配置创建 Cache
+func newCache(o cacheOptions) *Cache {
+	return newTypedCache(func(c *Cache) {
+		c.cacheConfig = o.cacheConfig
+		c.OnEvict(o.onEvict)
+	})
+}
+
+// NewTypedCache 创建泛型缓存
+func NewTypedCache[K comparable, V any](opts ...ConfigOption) *TypedCache[K, V] {
+	return newTypedCache(func(c *TypedCache[K, V]) {
+		for _, opt := range opts {
+			opt(&c.cacheConfig)
+		}
+	})
+}
+
+// newTypedCache 创建缓存，configure 应用选项之后再按配置创建淘汰队列、启动后台 goroutine
+func newTypedCache[K comparable, V any](configure func(c *TypedCache[K, V])) *TypedCache[K, V] {
+	c := &TypedCache[K, V]{
+		data:        make(map[K]*cacheEntry[K, V]),
+		calls:       make(map[K]*loadCall[V]),
+		failures:    make(map[K]loadFailure),
+		cacheConfig: cacheConfig{errorTTL: defaultErrorTTL},
+		now:         time.Now,
+		epoch:       time.Now(),
+	}
+	configure(c)
+	if c.maxEntries > 0 {
+		c.queue = newEvictionQueue[K, V](c.policy)
+	}
+	c.stop = make(chan struct{})
+	if c.janitorInterval > 0 {
+		c.background.Add(1)
+		go c.janitor()
+	}
+	if c.snapshot != nil {
+		c.background.Add(1)
+		go c.snapshotLoop()
+	}
+	return c
+}
+
+// OnEvict 设置条目过期或被淘汰时的回调，fn 为 nil 时取消。回调在释放锁之后执行，可以安全地访问缓存；
+// 回调的参数类型就是缓存的键值类型，类型不一致时编译报错。可以在任何时候调用，之后发生的过期和淘汰使用新的回调
+func (c *TypedCache[K, V]) OnEvict(fn func(key K, value V, reason EvictionReason)) {
+	if fn == nil {
+		c.onEvict.Store(nil)
+		return
+	}
+	c.onEvict.Store(&fn)
+}
+
+// Get 读取键对应的值，过期的条目视为不存在。只持有读锁，多个 goroutine 可以并发读取
+func (c *TypedCache[K, V]) Get(key K) (V, bool) {
+	now := c.now()
+	c.rwMutext.RLock()
+	entry, ok := c.data[key]
+	if !ok {
+		c.rwMutext.RUnlock()
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	if entry.expired(now) {
+		c.rwMutext.RUnlock()
+		c.misses.Add(1)
+		c.removeExpired(entry)
+		var zero V
+		return zero, false
+	}
+	value := entry.value
+	entry.touch(c.clock(now))
+	entry.hits.Add(1)
+	c.rwMutext.RUnlock()
+	c.hits.Add(1)
+	return value, true
+}
+
+// removeExpired 释放读锁之后再获取写锁删除过期条目；期间条目可能已被其他 goroutine 删除或覆盖，因此需要重新检查
+func (c *TypedCache[K, V]) removeExpired(entry *cacheEntry[K, V]) {
+	c.rwMutext.Lock()
+	if c.data[entry.key] != entry || !entry.expired(c.now()) {
+		c.rwMutext.Unlock()
+		return
+	}
+	c.remove(entry)
+	c.publish(EventExpire, entry)
+	c.rwMutext.Unlock()
+	c.expired.Add(1)
+	c.notifyEvicted([]*cacheEntry[K, V]{entry}, ReasonExpired)
+}
+
+// Set 写入键值，使用默认过期时间
+func (c *TypedCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.defaultTTL)
+}
+
+// SetWithTTL 写入键值并指定过期时间，ttl 为 0 表示永不过期
+func (c *TypedCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = c.now().Add(ttl)
+	}
+
+	c.rwMutext.Lock()
+	_, evicted := c.set(key, value, expireAt)
+	c.rwMutext.Unlock()
+	c.evicted.Add(int64(len(evicted)))
+	c.notifyEvicted(evicted, ReasonCapacity)
+}
+
+// set 写入或更新条目，返回该条目以及为腾出空间而被淘汰的条目，调用方需持有写锁
+func (c *TypedCache[K, V]) set(key K, value V, expireAt time.Time) (*cacheEntry[K, V], []*cacheEntry[K, V]) {
+	return c.put(key, value, expireAt, nil)
+}
+
+// put 与 set 相同，hits 非 nil 时同时把条目的访问次数设为 *hits（Restore 使用）。
+// 访问次数在条目进入淘汰队列之前设置，覆盖已有的键时重新调整它在队列中的位置——访问次数可能变小，
+// 而淘汰队列要求记录的 rank 不大于实际的 rank。带着访问次数写入的新条目先写入再淘汰，与已有条目一起比较，它本身也可能被淘汰
+func (c *TypedCache[K, V]) put(key K, value V, expireAt time.Time, hits *int64) (*cacheEntry[K, V], []*cacheEntry[K, V]) {
+	accessed := c.clock(c.now())
+	if entry, ok := c.data[key]; ok {
+		entry.value = value
+		entry.expireAt = expireAt
+		entry.touch(accessed)
+		if hits != nil {
+			entry.hits.Store(*hits)
+			c.queue.fix(entry)
+		}
+		c.publish(EventSet, entry)
+		return entry, nil
+	}
+	var evicted []*cacheEntry[K, V]
+	if hits == nil {
+		evicted = c.makeRoom(c.maxEntries - 1)
+	}
+	c.writes++
+	entry := &cacheEntry[K, V]{key: key, value: value, expireAt: expireAt, created: c.writes}
+	entry.accessed.Store(accessed)
+	if hits != nil {
+		entry.hits.Store(*hits)
+	}
+	c.data[key] = entry
+	c.queue.push(entry)
+	c.publish(EventSet, entry)
+	if hits != nil {
+		evicted = c.makeRoom(c.maxEntries)
+	}
+	return entry, evicted
+}
+
+// remove 从 map 和淘汰队列中删除条目，调用方需持有写锁
+func (c *TypedCache[K, V]) remove(entry *cacheEntry[K, V]) {
+	delete(c.data, entry.key)
+	c.queue.remove(entry)
+}
+
+// clock 把 now 换算为条目的访问时间。只读取时钟，不像共享的计数器那样需要所有读取写同一个变量
+func (c *TypedCache[K, V]) clock(now time.Time) int64 {
+	return int64(now.Sub(c.epoch))
+}
+
+// Delete 删除键，返回键是否存在
+func (c *TypedCache[K, V]) Delete(key K) bool {
+	c.rwMutext.Lock()
+	defer c.rwMutext.Unlock()
+	entry, ok := c.data[key]
+	if ok {
+		c.remove(entry)
+		c.publish(EventDelete, entry)
+	}
+	return ok
+}
+
+// Len 返回条目数量，可能包含已过期但尚未清理的条目
+func (c *TypedCache[K, V]) Len() int {
+	c.rwMutext.RLock()
+	defer c.rwMutext.RUnlock()
+	return len(c.data)
+}
+
+// Keys 返回所有未过期的键，顺序不固定
+func (c *TypedCache[K, V]) Keys() []K {
+	now := c.now()
+	c.rwMutext.RLock()
+	defer c.rwMutext.RUnlock()
+	keys := make([]K, 0, len(c.data))
+	for key, entry := range c.data {
+		if !entry.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// All 遍历所有未过期的键值对，顺序不固定。遍历的是调用时的副本，循环体中可以读写缓存；
+// 遍历不计入命中统计，也不影响淘汰顺序
+func (c *TypedCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := c.now()
+		c.rwMutext.RLock()
+		entries := make([]*cacheEntry[K, V], 0, len(c.data))
+		values := make([]V, 0, len(c.data))
+		for _, entry := range c.data {
+			if !entry.expired(now) {
+				entries = append(entries, entry)
+				values = append(values, entry.value)
+			}
+		}
+		c.rwMutext.RUnlock()
+		for i, entry := range entries {
+			if !yield(entry.key, values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// GetMany 批量读取，只返回命中的键值对
+func (c *TypedCache[K, V]) GetMany(keys ...K) map[K]V {
+	result := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := c.Get(key); ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// SetMany 批量写入，使用默认过期时间，整批写入只获取一次写锁
+func (c *TypedCache[K, V]) SetMany(items map[K]V) {
+	var expireAt time.Time
+	if c.defaultTTL > 0 {
+		expireAt = c.now().Add(c.defaultTTL)
+	}
+	var evicted []*cacheEntry[K, V]
+	c.rwMutext.Lock()
+	for key, value := range items {
+		_, removed := c.set(key, value, expireAt)
+		evicted = append(evicted, removed...)
+	}
+	c.rwMutext.Unlock()
+	c.evicted.Add(int64(len(evicted)))
+	c.notifyEvicted(evicted, ReasonCapacity)
+}
+
+// DeleteMany 批量删除，返回实际删除的键的数量
+func (c *TypedCache[K, V]) DeleteMany(keys ...K) int {
+	c.rwMutext.Lock()
+	defer c.rwMutext.Unlock()
+	n := 0
+	for _, key := range keys {
+		if entry, ok := c.data[key]; ok {
+			c.remove(entry)
+			c.publish(EventDelete, entry)
+			n++
+		}
+	}
+	return n
+}
+
+// Stats 返回统计信息，不需要加锁；并发读写时各计数之间不保证是同一时刻的快照
+func (c *TypedCache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:    int(c.hits.Load()),
+		Misses:  int(c.misses.Load()),
+		Expired: int(c.expired.Load()),
+		Evicted: int(c.evicted.Load()),
+
+		Loads:      int(c.loads.Load()),
+		LoadErrors: int(c.loadErrors.Load()),
+	}
+}
+
+// DeleteExpired 删除所有已过期的条目和 GetOrLoad 的过期负缓存，返回删除的条目数量
+func (c *TypedCache[K, V]) DeleteExpired() int {
+	now := c.now()
+	c.rwMutext.Lock()
+	var expired []*cacheEntry[K, V]
+	for _, entry := range c.data {
+		if entry.expired(now) {
+			c.remove(entry)
+			c.publish(EventExpire, entry)
+			expired = append(expired, entry)
+		}
+	}
+	c.rwMutext.Unlock()
+
+	// 顺便清理过期的负缓存，它们不计入返回值
+	c.loadMu.Lock()
+	c.pruneFailures(now)
+	c.loadMu.Unlock()
+
+	c.expired.Add(int64(len(expired)))
+	c.notifyEvicted(expired, ReasonExpired)
+	return len(expired)
+}
+
+// Close 停止后台清理和定期快照并等待其退出，然后关闭所有事件订阅，可以重复调用。开启了定期快照时，退出前会再写一次快照
+func (c *TypedCache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.background.Wait()
+		c.closeSubscribers()
+	})
+}
+
+// janitor 定期清理过期条目，直到 Close 被调用
+func (c *TypedCache[K, V]) janitor() {
+	defer c.background.Done()
+	ticker := time.NewTicker(c.janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// makeRoom 按策略淘汰条目，直到条目数不超过 limit，返回被淘汰的条目，调用方需持有写锁。
+// 写入新键时先以 maxEntries-1 为上限淘汰再写入，避免 LFU 策略下新写入（访问次数为 0）的条目被立即淘汰
+func (c *TypedCache[K, V]) makeRoom(limit int) []*cacheEntry[K, V] {
+	if c.maxEntries <= 0 {
+		return nil
+	}
+	var evicted []*cacheEntry[K, V]
+	for len(c.data) > limit {
+		victim := c.queue.pop()
+		delete(c.data, victim.key)
+		c.publish(EventEvict, victim)
+		evicted = append(evicted, victim)
+	}
+	return evicted
+}
+
+// notifyEvicted 在锁外调用淘汰回调
+func (c *TypedCache[K, V]) notifyEvicted(entries []*cacheEntry[K, V], reason EvictionReason) {
+	fn := c.onEvict.Load()
+	if fn == nil {
+		return
+	}
+	for _, entry := range entries {
+		(*fn)(entry.key, entry.value, reason)
+	}
+}
+
+// cacheEntry 缓存条目：value、expireAt 以及淘汰队列使用的字段只在持有写锁时修改；accessed 和 hits 在读锁下并发更新，因此是原子类型
+type cacheEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time    // 零值表示永不过期
+	created  uint64       // 写入序号，用于 FIFO
+	accessed atomic.Int64 // 最近一次访问或更新的时间，见 clock，用于 LRU
+	hits     atomic.Int64 // 访问次数，用于 LFU
+
+	index int          // 在淘汰队列中的下标
+	rank  evictionRank // 淘汰队列记录的 rank，可能小于实际的 rank
+}
+
+// touch 把访问时间推进到 at。now 在加锁之前读取，并发的读取可能以相反的顺序到达这里，
+// 只在 at 更大时写入，保证访问时间只增不减（淘汰队列依赖这一点，见 evictionQueue）
+func (e *cacheEntry[K, V]) touch(at int64) {
+	for {
+		old := e.accessed.Load()
+		if at <= old || e.accessed.CompareAndSwap(old, at) {
+			return
+		}
+	}
+}
+
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
@@ -0,0 +1,172 @@
+package sync
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// EventType 缓存事件的类型
+type EventType int
+
+const (
+	EventSet    EventType = iota // 写入，包括新增和覆盖
+	EventDelete                  // 被 Delete / DeleteMany 删除
+	EventExpire                  // 过期后被删除（读取时发现或后台清理）
+	EventEvict                   // 条目数达到上限时被淘汰
+
+	eventTypeCount // 事件类型的数量，新增类型加在它之前
+)
+
+// eventMask 订阅关心的事件类型的位掩码，每种类型占一位
+type eventMask uint8
+
+// 事件类型超出掩码的位数时编译失败，需要换用更宽的 eventMask
+var _ [8 - eventTypeCount]struct{}
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event 一次缓存变更
+type Event[K comparable, V any] struct {
+	Type     EventType
+	Key      K
+	Value    V         // EventSet 为写入的新值，其余为被移除的值
+	ExpireAt time.Time // 条目的过期时间，零值表示永不过期
+	Time     time.Time // 事件发生的时间
+}
+
+// SlowSubscriberPolicy 订阅者的缓冲区已满时如何处理新事件。无论哪种策略，缓存的写操作都不会等待订阅者
+type SlowSubscriberPolicy int
+
+const (
+	DropNewest SlowSubscriberPolicy = iota // 丢弃新事件，保留缓冲区中较早的事件
+	DropOldest                             // 丢弃缓冲区中最早的事件，为新事件腾出位置，适合只关心最新状态的订阅者
+)
+
+// Subscription 一个事件订阅。事件按发生的顺序写入 C，C 在订阅或缓存被关闭后关闭
+type Subscription[K comparable, V any] struct {
+	C <-chan Event[K, V]
+
+	ch      chan Event[K, V]
+	policy  SlowSubscriberPolicy
+	types   eventMask // 关心的事件类型
+	dropped atomic.Int64
+	cache   *TypedCache[K, V]
+}
+
+// Dropped 返回因缓冲区已满而丢弃的事件数
+func (s *Subscription[K, V]) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅并关闭 C，可以重复调用
+func (s *Subscription[K, V]) Close() {
+	c := s.cache
+	c.rwMutext.Lock()
+	defer c.rwMutext.Unlock()
+	for i, sub := range c.subscribers {
+		if sub == s {
+			c.subscribers = append(c.subscribers[:i], c.subscribers[i+1:]...)
+			close(s.ch)
+			return
+		}
+	}
+}
+
+// send 不阻塞地投递事件，调用方持有缓存的写锁，因此同一个订阅不会被并发投递
+func (s *Subscription[K, V]) send(event Event[K, V]) {
+	select {
+	case s.ch <- event:
+		return
+	default:
+	}
+	if s.policy == DropNewest {
+		s.dropped.Add(1)
+		return
+	}
+	select {
+	case <-s.ch:
+		s.dropped.Add(1)
+	default: // 订阅者恰好读走了一个事件
+	}
+	// 只有持有写锁的 goroutine 会发送，腾出的位置不会被别人占用
+	s.ch <- event
+}
+
+// Subscribe 订阅缓存的变更事件，types 为空时订阅全部类型，包含未定义的类型时 panic。事件先进入容量为 buffer（至少为 1）的缓冲区，
+// 缓冲区满时按 policy 丢弃，丢弃的数量可以通过 Dropped 查看。
+//
+// 事件在缓存持有写锁时投递，因此同一个订阅收到的事件顺序与缓存状态的变化顺序一致；
+// 投递只是一次非阻塞的通道发送，订阅者处理得再慢也不会拖慢写操作。不再需要时调用 Close 取消订阅。
+func (c *TypedCache[K, V]) Subscribe(buffer int, policy SlowSubscriberPolicy, types ...EventType) *Subscription[K, V] {
+	ch := make(chan Event[K, V], max(buffer, 1))
+	s := &Subscription[K, V]{C: ch, ch: ch, policy: policy, cache: c}
+	for _, t := range types {
+		if t < 0 || t >= eventTypeCount {
+			panic(fmt.Sprintf("sync: 未定义的事件类型 %d", int(t)))
+		}
+		s.types |= 1 << t
+	}
+	if len(types) == 0 {
+		s.types = ^eventMask(0)
+	}
+
+	c.rwMutext.Lock()
+	defer c.rwMutext.Unlock()
+	select {
+	case <-c.stop:
+		close(ch) // 缓存已关闭，不会再有事件
+	default:
+		c.subscribers = append(c.subscribers, s)
+	}
+	return s
+}
+
+// SubscribeFunc 与 Subscribe 相同，但由一个独立的 goroutine 依次对每个事件调用 fn，
+// fn 处理不过来时事件按 policy 丢弃。返回的订阅的 C 由该 goroutine 读取，调用方不要再读；
+// Close 之后 fn 处理完缓冲区中剩余的事件就会退出
+func (c *TypedCache[K, V]) SubscribeFunc(buffer int, policy SlowSubscriberPolicy, fn func(Event[K, V]), types ...EventType) *Subscription[K, V] {
+	s := c.Subscribe(buffer, policy, types...)
+	go func() {
+		for event := range s.C {
+			fn(event)
+		}
+	}()
+	return s
+}
+
+// publish 向所有关心该类型的订阅者投递事件，调用方必须持有写锁
+func (c *TypedCache[K, V]) publish(t EventType, entry *cacheEntry[K, V]) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	event := Event[K, V]{Type: t, Key: entry.key, Value: entry.value, ExpireAt: entry.expireAt, Time: c.now()}
+	for _, s := range c.subscribers {
+		if s.types&(1<<t) != 0 {
+			s.send(event)
+		}
+	}
+}
+
+// closeSubscribers 关闭所有订阅，由 Close 调用
+func (c *TypedCache[K, V]) closeSubscribers() {
+	c.rwMutext.Lock()
+	defer c.rwMutext.Unlock()
+	for _, s := range c.subscribers {
+		close(s.ch)
+	}
+	c.subscribers = nil
+}
@@ -0,0 +1,115 @@
+package sync
+
+import "container/heap"
+
+// evictionRank 条目在淘汰顺序中的位置，越小越先被淘汰；相同时先写入的先被淘汰
+type evictionRank struct {
+	primary, secondary int64
+}
+
+func (r evictionRank) less(o evictionRank) bool {
+	if r.primary != o.primary {
+		return r.primary < o.primary
+	}
+	return r.secondary < o.secondary
+}
+
+// evictionQueue 按淘汰顺序排列条目的最小堆，只在持有写锁时修改。未设置最大条目数的缓存不需要它，为 nil
+//
+// Get 只持有读锁，不能调整堆，所以堆中记录的是条目入堆或上次调整时的 rank。访问时间和访问次数只增不减，
+// 记录的 rank 总是不大于实际的 rank：堆顶记录的 rank 与实际相同时，它就是真正最应被淘汰的条目；
+// 不同时按实际 rank 调整后再看新的堆顶。每个条目在两次淘汰之间最多被调整一次，淘汰的均摊开销为 O(log n)
+type evictionQueue[K comparable, V any] struct {
+	policy  EvictionPolicy
+	entries []*cacheEntry[K, V]
+}
+
+func newEvictionQueue[K comparable, V any](policy EvictionPolicy) *evictionQueue[K, V] {
+	return &evictionQueue[K, V]{policy: policy}
+}
+
+// rank 按淘汰策略计算条目当前的 rank
+func (q *evictionQueue[K, V]) rank(entry *cacheEntry[K, V]) evictionRank {
+	switch q.policy {
+	case EvictLFU:
+		return evictionRank{entry.hits.Load(), entry.accessed.Load()}
+	case EvictFIFO:
+		return evictionRank{} // 只按写入顺序
+	default:
+		return evictionRank{entry.accessed.Load(), 0}
+	}
+}
+
+// push 加入新写入的条目
+func (q *evictionQueue[K, V]) push(entry *cacheEntry[K, V]) {
+	if q == nil {
+		return
+	}
+	entry.rank = q.rank(entry)
+	heap.Push(q, entry)
+}
+
+// remove 移除被删除或过期的条目
+func (q *evictionQueue[K, V]) remove(entry *cacheEntry[K, V]) {
+	if q == nil {
+		return
+	}
+	heap.Remove(q, entry.index)
+}
+
+// fix 条目的 rank 可能变小时（Restore 改写访问次数）按实际的 rank 调整位置
+func (q *evictionQueue[K, V]) fix(entry *cacheEntry[K, V]) {
+	if q == nil {
+		return
+	}
+	entry.rank = q.rank(entry)
+	heap.Fix(q, entry.index)
+}
+
+// pop 移除并返回最应被淘汰的条目，队列不能为空
+func (q *evictionQueue[K, V]) pop() *cacheEntry[K, V] {
+	for {
+		top := q.entries[0]
+		if rank := q.rank(top); rank != top.rank {
+			top.rank = rank
+			heap.Fix(q, 0)
+			continue
+		}
+		return heap.Pop(q).(*cacheEntry[K, V])
+	}
+}
+
+// 以下方法实现 heap.Interface，只由 container/heap 调用
+
+func (q *evictionQueue[K, V]) Len() int {
+	return len(q.entries)
+}
+
+func (q *evictionQueue[K, V]) Less(i, j int) bool {
+	a, b := q.entries[i], q.entries[j]
+	if a.rank != b.rank {
+		return a.rank.less(b.rank)
+	}
+	return a.created < b.created
+}
+
+func (q *evictionQueue[K, V]) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+	q.entries[i].index = i
+	q.entries[j].index = j
+}
+
+func (q *evictionQueue[K, V]) Push(x any) {
+	entry := x.(*cacheEntry[K, V])
+	entry.index = len(q.entries)
+	q.entries = append(q.entries, entry)
+}
+
+func (q *evictionQueue[K, V]) Pop() any {
+	last := len(q.entries) - 1
+	entry := q.entries[last]
+	q.entries[last] = nil // 避免底层数组继续引用已淘汰的条目
+	q.entries = q.entries[:last]
+	entry.index = -1
+	return entry
+}
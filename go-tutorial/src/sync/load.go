@@ -0,0 +1,141 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoaderFunc 缓存未命中时加载键对应的值
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Loader Cache 使用的加载函数
+type Loader = LoaderFunc[string, interface{}]
+
+// defaultErrorTTL 加载失败的结果默认缓存的时间
+const defaultErrorTTL = time.Second
+
+// minFailureSweep 负缓存至少积累这么多条后才在写入时清理过期的错误
+const minFailureSweep = 64
+
+// loadCall 一次正在进行的加载，同一个键的并发未命中共享同一次加载
+type loadCall[V any] struct {
+	done  chan struct{} // 加载完成后关闭
+	value V
+	err   error
+}
+
+// loadFailure 缓存的加载错误（负缓存）
+type loadFailure struct {
+	err      error
+	expireAt time.Time
+}
+
+// WithErrorTTL 设置 GetOrLoad 加载失败时错误被缓存的时间，期间对该键的 GetOrLoad 直接返回该错误而不再调用 loader；
+// 默认为 1 秒，0 表示不缓存错误
+func WithErrorTTL(ttl time.Duration) ConfigOption {
+	return func(c *cacheConfig) {
+		c.errorTTL = ttl
+	}
+}
+
+// GetOrLoad 读取键对应的值，未命中时调用 loader 加载并写入缓存（使用默认过期时间）。
+// 同一个键的并发未命中只会调用一次 loader，其余调用方等待并共享结果；loader 返回的错误会被缓存 errorTTL 时间。
+//
+// loader 在独立的 goroutine 中运行，使用的 ctx 保留调用方 ctx 中的值但不会随之取消，
+// 因此某个调用方取消或超时只会让它自己返回 ctx.Err()，不影响其他等待者和加载本身。
+// loader 发生 panic 时，所有等待者都会收到错误。
+func (c *TypedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	c.loadMu.Lock()
+	// 加载完成时会先写入缓存再移除 loadCall，因此在 loadMu 下重新检查一次，避免刚完成的加载被重复执行
+	if value, ok := c.peek(key); ok {
+		c.loadMu.Unlock()
+		return value, nil
+	}
+	if failure, ok := c.failures[key]; ok {
+		if c.now().Before(failure.expireAt) {
+			c.loadMu.Unlock()
+			var zero V
+			return zero, failure.err
+		}
+		delete(c.failures, key)
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		call = &loadCall[V]{done: make(chan struct{})}
+		c.calls[key] = call
+		go c.load(context.WithoutCancel(ctx), key, loader, call)
+	}
+	c.loadMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// load 执行 loader，成功时写入缓存，失败时记录负缓存，最后唤醒所有等待者
+func (c *TypedCache[K, V]) load(ctx context.Context, key K, loader LoaderFunc[K, V], call *loadCall[V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero V
+			call.value, call.err = zero, fmt.Errorf("sync: 加载 %v 时发生 panic: %v", key, r)
+			c.loadErrors.Add(1)
+		}
+		if call.err == nil {
+			c.Set(key, call.value)
+		}
+		c.loadMu.Lock()
+		delete(c.calls, key)
+		if call.err != nil && c.errorTTL > 0 {
+			now := c.now()
+			// 没有开启后台清理时，不再被访问的键的错误只能在这里清理：
+			// 负缓存每增长一倍清理一次，均摊到每次写入的开销为 O(1)
+			if len(c.failures) >= max(c.sweepAt, minFailureSweep) {
+				c.pruneFailures(now)
+				c.sweepAt = 2 * len(c.failures)
+			}
+			c.failures[key] = loadFailure{err: call.err, expireAt: now.Add(c.errorTTL)}
+		}
+		c.loadMu.Unlock()
+		close(call.done)
+	}()
+	c.loads.Add(1)
+	call.value, call.err = loader(ctx, key)
+	if call.err != nil {
+		c.loadErrors.Add(1)
+	}
+}
+
+// pruneFailures 删除过期的负缓存，调用方需持有 loadMu
+func (c *TypedCache[K, V]) pruneFailures(now time.Time) {
+	for key, failure := range c.failures {
+		if !now.Before(failure.expireAt) {
+			delete(c.failures, key)
+		}
+	}
+}
+
+// peek 读取未过期的值，不更新统计和访问信息
+func (c *TypedCache[K, V]) peek(key K) (V, bool) {
+	c.rwMutext.RLock()
+	defer c.rwMutext.RUnlock()
+	entry, ok := c.data[key]
+	if !ok || entry.expired(c.now()) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// GetOrLoad 在键所在的分片上读取或加载
+func (s *ShardedCache) GetOrLoad(ctx context.Context, key string, loader Loader) (interface{}, error) {
+	return s.shard(key).GetOrLoad(ctx, key, loader)
+}
@@ -0,0 +1,409 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry 指标注册表，是 SafeCounter 的演进版：按名称注册带标签的计数器（Counter）、仪表（Gauge）和直方图（Histogram），
+// 并以 Prometheus 文本格式输出。Registry 实现了 http.Handler，可以直接挂在 /metrics 上。
+//
+// SafeCounter 的每次 Increment 都要争抢同一把锁；这里的指标都用原子操作更新，
+// 计数器和直方图还把数据分散到 stripeCount 个条带上，每次更新随机选择一个条带，读取时再汇总，
+// 大量 goroutine 同时更新同一个指标时也不会集中竞争同一个缓存行。
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// NewRegistry 创建空的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// collector 一个指标族（同名、不同标签值的一组指标）
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// register 注册指标族。同一名称重复注册时，类型和标签名都相同则返回已有的指标族，否则 panic；
+// 名称不合法同样 panic，这类错误应该在开发阶段暴露
+func register[M metric](r *Registry, name, help, typ string, labelNames []string, newMetric func() M) *metricVec[M] {
+	if !metricNameRE.MatchString(name) {
+		panic(fmt.Sprintf("sync: 指标名 %q 不合法", name))
+	}
+	for _, label := range labelNames {
+		if !labelNameRE.MatchString(label) || strings.HasPrefix(label, "__") || typ == "histogram" && label == "le" {
+			panic(fmt.Sprintf("sync: 指标 %s 的标签名 %q 不合法", name, label))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.collectors[name]; ok {
+		if vec, ok := existing.(*metricVec[M]); ok && vec.typ == typ && slices.Equal(vec.labelNames, labelNames) {
+			return vec
+		}
+		panic(fmt.Sprintf("sync: 指标 %s 已经以不同的类型或标签注册过", name))
+	}
+	vec := &metricVec[M]{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: slices.Clone(labelNames),
+		newMetric:  newMetric,
+		series:     make(map[string]*labeledMetric[M]),
+	}
+	r.collectors[name] = vec
+	return vec
+}
+
+// Counter 注册（或取回已注册的）计数器族
+func (r *Registry) Counter(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{register(r, name, help, "counter", labelNames, func() *Counter { return new(Counter) })}
+}
+
+// Gauge 注册（或取回已注册的）仪表族
+func (r *Registry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{register(r, name, help, "gauge", labelNames, func() *Gauge { return new(Gauge) })}
+}
+
+// Histogram 注册（或取回已注册的）直方图族。buckets 是严格递增的桶上界，不需要包含 +Inf，为 nil 时使用 DefaultBuckets；
+// 重复注册时沿用第一次注册的桶
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if n := len(buckets); n > 0 && math.IsInf(buckets[n-1], 1) {
+		buckets = buckets[:n-1]
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			panic(fmt.Sprintf("sync: 直方图 %s 的桶上界必须严格递增", name))
+		}
+	}
+	buckets = slices.Clone(buckets)
+	return &HistogramVec{register(r, name, help, "histogram", labelNames, func() *Histogram { return newHistogram(buckets) })}
+}
+
+// WriteTo 按 Prometheus 文本格式（0.0.4）写出所有指标，指标族按名称排序，同一族内按标签值排序
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, len(names))
+	slices.Sort(names)
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mu.RUnlock()
+
+	// 先写入内存再一次性输出，避免慢客户端期间持有任何锁
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	for _, c := range collectors {
+		c.write(writer)
+	}
+	writer.Flush()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP 以 Prometheus 文本格式响应，例如 http.Handle("/metrics", registry)
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// metric 单个指标（一组确定的标签值）
+type metric interface {
+	// writeSamples 写出样本行，labels 是格式化好的 `k="v",...`，没有标签时为空
+	writeSamples(w *bufio.Writer, name, labels string)
+}
+
+// metricVec 同名指标按标签值区分的集合
+type metricVec[M metric] struct {
+	name, help, typ string
+	labelNames      []string
+	newMetric       func() M
+
+	mu     sync.RWMutex
+	series map[string]*labeledMetric[M]
+}
+
+type labeledMetric[M metric] struct {
+	values []string
+	metric M
+}
+
+// with 返回标签值对应的指标，不存在时创建。已存在的指标只需要读锁，调用方也可以保存返回值避免每次查找
+func (v *metricVec[M]) with(values []string) M {
+	if len(values) != len(v.labelNames) {
+		panic(fmt.Sprintf("sync: 指标 %s 需要 %d 个标签值，实际为 %d 个", v.name, len(v.labelNames), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s.metric
+	}
+	s = &labeledMetric[M]{values: slices.Clone(values), metric: v.newMetric()}
+	v.series[key] = s
+	return s.metric
+}
+
+func (v *metricVec[M]) write(w *bufio.Writer) {
+	v.mu.RLock()
+	series := make([]*labeledMetric[M], 0, len(v.series))
+	for _, s := range v.series {
+		series = append(series, s)
+	}
+	v.mu.RUnlock()
+	slices.SortFunc(series, func(a, b *labeledMetric[M]) int {
+		return slices.Compare(a.values, b.values)
+	})
+
+	if v.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+	for _, s := range series {
+		var labels strings.Builder
+		for i, name := range v.labelNames {
+			if i > 0 {
+				labels.WriteByte(',')
+			}
+			labels.WriteString(name + `="` + escapeLabelValue(s.values[i]) + `"`)
+		}
+		s.metric.writeSamples(w, v.name, labels.String())
+	}
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+// writeSample 写出一行样本
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// atomicFloat 用 CAS 实现的原子 float64
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) Store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) Add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// CounterVec 按标签区分的计数器族
+type CounterVec struct {
+	vec *metricVec[*Counter]
+}
+
+// With 返回标签值对应的计数器，标签值的个数和顺序与注册时的标签名一致，个数不对时 panic
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	return v.vec.with(labelValues)
+}
+
+// Counter 只增不减的计数器
+type Counter struct {
+	n stripedCounter
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add 增加 n，n 为负数时 panic
+func (c *Counter) Add(n int64) {
+	if n < 0 {
+		panic("sync: 计数器不能减少")
+	}
+	c.n.Add(n)
+}
+
+// Value 汇总所有条带的值
+func (c *Counter) Value() int64 {
+	return c.n.Load()
+}
+
+func (c *Counter) writeSamples(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, float64(c.Value()))
+}
+
+// GaugeVec 按标签区分的仪表族
+type GaugeVec struct {
+	vec *metricVec[*Gauge]
+}
+
+// With 返回标签值对应的仪表
+func (v *GaugeVec) With(labelValues ...string) *Gauge {
+	return v.vec.with(labelValues)
+}
+
+// Gauge 可增可减、也可以直接设置的值，例如队列长度、内存用量。Set 需要一个确定的当前值，因此不分条带
+type Gauge struct {
+	value atomicFloat
+}
+
+func (g *Gauge) Set(v float64)  { g.value.Store(v) }
+func (g *Gauge) Add(v float64)  { g.value.Add(v) }
+func (g *Gauge) Sub(v float64)  { g.value.Add(-v) }
+func (g *Gauge) Inc()           { g.value.Add(1) }
+func (g *Gauge) Dec()           { g.value.Add(-1) }
+func (g *Gauge) Value() float64 { return g.value.Load() }
+
+func (g *Gauge) writeSamples(w *bufio.Writer, name, labels string) {
+	writeSample(w, name, labels, g.Value())
+}
+
+// DefaultBuckets 默认的直方图桶上界（单位为秒），适合衡量网络请求的耗时
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramVec 按标签区分的直方图族
+type HistogramVec struct {
+	vec *metricVec[*Histogram]
+}
+
+// With 返回标签值对应的直方图
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	return v.vec.with(labelValues)
+}
+
+// Histogram 按桶统计观测值的分布，同时记录观测值的总和与个数
+type Histogram struct {
+	upperBounds []float64
+	stripes     [stripeCount]struct {
+		counts []atomic.Uint64 // 落在每个桶中的个数（非累计），最后一个是 +Inf 桶
+		sum    atomicFloat
+		_      [32]byte
+	}
+}
+
+func newHistogram(upperBounds []float64) *Histogram {
+	h := &Histogram{upperBounds: upperBounds}
+	for i := range h.stripes {
+		h.stripes[i].counts = make([]atomic.Uint64, len(upperBounds)+1)
+	}
+	return h
+}
+
+// Observe 记录一个观测值，值等于某个桶的上界时计入该桶
+func (h *Histogram) Observe(v float64) {
+	stripe := &h.stripes[randomStripe()]
+	stripe.counts[sort.SearchFloat64s(h.upperBounds, v)].Add(1)
+	stripe.sum.Add(v)
+}
+
+// ObserveSince 记录从 start 到现在经过的秒数，通常配合 defer 使用
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// snapshot 汇总所有条带，返回每个桶的累计个数（最后一个即总数）和总和
+func (h *Histogram) snapshot() ([]uint64, float64) {
+	cumulative := make([]uint64, len(h.upperBounds)+1)
+	var sum float64
+	for i := range h.stripes {
+		for j := range h.stripes[i].counts {
+			cumulative[j] += h.stripes[i].counts[j].Load()
+		}
+		sum += h.stripes[i].sum.Load()
+	}
+	for j := 1; j < len(cumulative); j++ {
+		cumulative[j] += cumulative[j-1]
+	}
+	return cumulative, sum
+}
+
+// Count 返回观测值的个数
+func (h *Histogram) Count() uint64 {
+	cumulative, _ := h.snapshot()
+	return cumulative[len(cumulative)-1]
+}
+
+// Sum 返回观测值的总和
+func (h *Histogram) Sum() float64 {
+	_, sum := h.snapshot()
+	return sum
+}
+
+func (h *Histogram) writeSamples(w *bufio.Writer, name, labels string) {
+	cumulative, sum := h.snapshot()
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+	for i, count := range cumulative {
+		bound := math.Inf(1)
+		if i < len(h.upperBounds) {
+			bound = h.upperBounds[i]
+		}
+		writeSample(w, name+"_bucket", prefix+`le="`+formatFloat(bound)+`"`, float64(count))
+	}
+	writeSample(w, name+"_sum", labels, sum)
+	writeSample(w, name+"_count", labels, float64(cumulative[len(cumulative)-1]))
+}
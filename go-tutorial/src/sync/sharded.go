@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"sync"
+	"time"
+)
+
+// ShardedCache 分片缓存：按键的哈希值把条目分散到多个独立加锁的 Cache 中，
+// 不同分片上的读写互不阻塞，适合读写都很频繁、单把锁成为瓶颈的场景。
+// 过期时间、淘汰策略等行为与 Cache 相同，但淘汰在各分片内独立进行。
+type ShardedCache struct {
+	shards []*Cache
+	hash   func(key string) uint64
+
+	snapshot   *snapshotConfig // 定期快照由 ShardedCache 统一写入，分片自己不写
+	stop       chan struct{}
+	background sync.WaitGroup
+	closeOnce  sync.Once
+}
+
+// NewShardedCache 创建有 shards 个分片的缓存，hash 为 nil 时使用 FNV-1a。
+// opts 应用到每个分片；WithMaxEntries 设置的是总条目数，会平均分配到各分片（向上取整）；
+// WithPeriodicSnapshot 不应用到分片，而是定期把所有分片合并为一个快照写入文件
+func NewShardedCache(shards int, hash func(key string) uint64, opts ...CacheOption) *ShardedCache {
+	shards = max(shards, 1)
+	if hash == nil {
+		hash = fnvHash
+	}
+	options := parseCacheOptions(opts)
+	s := &ShardedCache{shards: make([]*Cache, shards), hash: hash, snapshot: options.snapshot, stop: make(chan struct{})}
+	// 各分片写同一个文件会互相覆盖，最后只剩一个分片的条目
+	options.snapshot = nil
+	options.maxEntries = (options.maxEntries + shards - 1) / shards
+	for i := range s.shards {
+		s.shards[i] = newCache(options)
+	}
+	if s.snapshot != nil {
+		s.background.Add(1)
+		go func() {
+			defer s.background.Done()
+			s.snapshot.run(s.stop, s.SaveSnapshot)
+		}()
+	}
+	return s
+}
+
+// fnvHash 默认的键哈希函数（64 位 FNV-1a），逐字节计算，避免 hash/fnv 每次调用的内存分配
+func fnvHash(key string) uint64 {
+	const offset, prime = 14695981039346656037, 1099511628211
+	h := uint64(offset)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime
+	}
+	return h
+}
+
+// shard 返回键所在的分片
+func (s *ShardedCache) shard(key string) *Cache {
+	return s.shards[s.hash(key)%uint64(len(s.shards))]
+}
+
+func (s *ShardedCache) Get(key string) (interface{}, bool) {
+	return s.shard(key).Get(key)
+}
+
+func (s *ShardedCache) Set(key string, value interface{}) {
+	s.shard(key).Set(key, value)
+}
+
+func (s *ShardedCache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	s.shard(key).SetWithTTL(key, value, ttl)
+}
+
+func (s *ShardedCache) Delete(key string) bool {
+	return s.shard(key).Delete(key)
+}
+
+// Shards 返回分片数
+func (s *ShardedCache) Shards() int {
+	return len(s.shards)
+}
+
+// Len 返回所有分片的条目总数
+func (s *ShardedCache) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Stats 汇总所有分片的统计信息
+func (s *ShardedCache) Stats() CacheStats {
+	var total CacheStats
+	for _, shard := range s.shards {
+		stats := shard.Stats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Expired += stats.Expired
+		total.Evicted += stats.Evicted
+		total.Loads += stats.Loads
+		total.LoadErrors += stats.LoadErrors
+	}
+	return total
+}
+
+// DeleteExpired 逐个分片删除过期条目，返回删除的总数
+func (s *ShardedCache) DeleteExpired() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.DeleteExpired()
+	}
+	return n
+}
+
+// Close 停止所有分片的后台清理和定期快照，可以重复调用。开启了定期快照时，退出前会再写一次快照
+func (s *ShardedCache) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		s.background.Wait()
+		for _, shard := range s.shards {
+			shard.Close()
+		}
+	})
+}
+
+// Snapshot 把所有分片的快照合并为一个，格式与 Cache 的快照相同，两者的快照文件可以互相加载。
+// 各分片依次生成快照，分片之间不是同一时刻的状态
+func (s *ShardedCache) Snapshot() *Snapshot {
+	snapshot := &Snapshot{TakenAt: s.shards[0].now()}
+	for _, shard := range s.shards {
+		part := shard.Snapshot()
+		snapshot.Entries = append(snapshot.Entries, part.Entries...)
+	}
+	snapshot.Stats = s.Stats()
+	return snapshot
+}
+
+// Restore 把快照中的条目按键分配到各分片并恢复，条目在分片内保持快照中的顺序；统计信息替换为快照中的统计
+func (s *ShardedCache) Restore(snapshot *Snapshot) {
+	parts := make([]Snapshot, len(s.shards))
+	for _, e := range snapshot.Entries {
+		i := s.hash(e.Key) % uint64(len(s.shards))
+		parts[i].Entries = append(parts[i].Entries, e)
+	}
+	parts[0].Stats = snapshot.Stats // 统计只在汇总时有意义，全部记到第一个分片，其余分片清零
+	for i, shard := range s.shards {
+		parts[i].TakenAt = snapshot.TakenAt
+		shard.Restore(&parts[i])
+	}
+}
+
+// SaveSnapshot 把合并后的快照写入 path，写入方式与 Cache.SaveSnapshot 相同
+func (s *ShardedCache) SaveSnapshot(path string, codec SnapshotCodec) error {
+	return writeSnapshot(path, codec, s.Snapshot())
+}
+
+// LoadSnapshot 从 path 读取快照并恢复到各分片，文件不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+func (s *ShardedCache) LoadSnapshot(path string, codec SnapshotCodec) error {
+	var snapshot Snapshot
+	if err := readSnapshot(path, codec, &snapshot); err != nil {
+		return err
+	}
+	s.Restore(&snapshot)
+	return nil
+}
@@ -0,0 +1,222 @@
+package sync
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// TypedSnapshot 缓存在某一时刻的快照，包含所有未过期条目的值、过期时间和统计信息
+type TypedSnapshot[K comparable, V any] struct {
+	TakenAt time.Time                  `json:"taken_at"`
+	Entries []TypedSnapshotEntry[K, V] `json:"entries"`
+	Stats   CacheStats                 `json:"stats"`
+}
+
+// Snapshot Cache 的快照
+type Snapshot = TypedSnapshot[string, interface{}]
+
+// TypedSnapshotEntry 快照中的一个条目，按最近访问时间从旧到新排列，恢复时按该顺序写入以保留 LRU 顺序
+type TypedSnapshotEntry[K comparable, V any] struct {
+	Key      K         `json:"key"`
+	Value    V         `json:"value"`
+	ExpireAt time.Time `json:"expire_at"` // 零值表示永不过期
+	Hits     int64     `json:"hits"`      // 访问次数，恢复后 LFU 策略仍然有效
+}
+
+// SnapshotEntry Cache 快照中的条目
+type SnapshotEntry = TypedSnapshotEntry[string, interface{}]
+
+// SnapshotCodec 快照的编解码器，snapshot 是指向 TypedSnapshot 的指针，同一个编解码器可以用于任意键值类型
+type SnapshotCodec interface {
+	Encode(w io.Writer, snapshot any) error
+	Decode(r io.Reader, snapshot any) error
+}
+
+// GobCodec 使用 encoding/gob 编解码快照，能还原值的具体类型；
+// 自定义类型的值需要先用 gob.Register 注册，基础类型不需要
+type GobCodec struct{}
+
+func (GobCodec) Encode(w io.Writer, snapshot any) error {
+	return gob.NewEncoder(w).Encode(snapshot)
+}
+
+func (GobCodec) Decode(r io.Reader, snapshot any) error {
+	return gob.NewDecoder(r).Decode(snapshot)
+}
+
+// JSONCodec 使用 encoding/json 编解码快照，便于查看和跨语言使用；
+// 值为 interface{} 时会按 JSON 的规则还原（数字变为 float64，结构体变为 map[string]interface{}），使用 TypedCache 时能还原为 V
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(w io.Writer, snapshot any) error {
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+func (JSONCodec) Decode(r io.Reader, snapshot any) error {
+	return json.NewDecoder(r).Decode(snapshot)
+}
+
+// Snapshot 生成缓存的快照。只在复制条目时持有读锁，Get 不受影响；编码和写文件都在锁外进行。
+// 条目的值是浅拷贝，值为指针、切片或 map 时不要在快照编码完成之前修改其内容
+func (c *TypedCache[K, V]) Snapshot() *TypedSnapshot[K, V] {
+	now := c.now()
+	type ordered struct {
+		TypedSnapshotEntry[K, V]
+		accessed int64
+		created  uint64
+	}
+	c.rwMutext.RLock()
+	entries := make([]ordered, 0, len(c.data))
+	for key, entry := range c.data {
+		if entry.expired(now) {
+			continue
+		}
+		entries = append(entries, ordered{
+			TypedSnapshotEntry: TypedSnapshotEntry[K, V]{Key: key, Value: entry.value, ExpireAt: entry.expireAt, Hits: entry.hits.Load()},
+			accessed:           entry.accessed.Load(),
+			created:            entry.created,
+		})
+	}
+	stats := c.Stats()
+	c.rwMutext.RUnlock()
+
+	slices.SortFunc(entries, func(a, b ordered) int {
+		// 同一时刻访问的条目按写入顺序，与淘汰队列一致
+		return cmp.Or(cmp.Compare(a.accessed, b.accessed), cmp.Compare(a.created, b.created))
+	})
+	snapshot := &TypedSnapshot[K, V]{TakenAt: now, Stats: stats, Entries: make([]TypedSnapshotEntry[K, V], len(entries))}
+	for i, entry := range entries {
+		snapshot.Entries[i] = entry.TypedSnapshotEntry
+	}
+	return snapshot
+}
+
+// Restore 把快照中尚未过期的条目写入缓存（覆盖同名的键），并用快照中的统计信息替换当前统计。
+// 过期时间是绝对时间，服务停止期间到期的条目不会被恢复；条目数超出上限时按淘汰策略淘汰
+func (c *TypedCache[K, V]) Restore(snapshot *TypedSnapshot[K, V]) {
+	now := c.now()
+	var evicted []*cacheEntry[K, V]
+	c.rwMutext.Lock()
+	for _, e := range snapshot.Entries {
+		if !e.ExpireAt.IsZero() && !now.Before(e.ExpireAt) {
+			continue
+		}
+		_, removed := c.put(e.Key, e.Value, e.ExpireAt, &e.Hits)
+		evicted = append(evicted, removed...)
+	}
+	c.hits.Store(int64(snapshot.Stats.Hits))
+	c.misses.Store(int64(snapshot.Stats.Misses))
+	c.expired.Store(int64(snapshot.Stats.Expired))
+	c.evicted.Store(int64(snapshot.Stats.Evicted + len(evicted)))
+	c.loads.Store(int64(snapshot.Stats.Loads))
+	c.loadErrors.Store(int64(snapshot.Stats.LoadErrors))
+	c.rwMutext.Unlock()
+	c.notifyEvicted(evicted, ReasonCapacity)
+}
+
+// SaveSnapshot 把快照写入 path：先写临时文件并同步到磁盘，再重命名覆盖，写入过程中崩溃不会破坏已有的快照
+func (c *TypedCache[K, V]) SaveSnapshot(path string, codec SnapshotCodec) error {
+	return writeSnapshot(path, codec, c.Snapshot())
+}
+
+// LoadSnapshot 从 path 读取快照并恢复到缓存。文件不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)，
+// 首次启动时可以忽略
+func (c *TypedCache[K, V]) LoadSnapshot(path string, codec SnapshotCodec) error {
+	var snapshot TypedSnapshot[K, V]
+	if err := readSnapshot(path, codec, &snapshot); err != nil {
+		return err
+	}
+	c.Restore(&snapshot)
+	return nil
+}
+
+// writeSnapshot 编码快照并原子地替换 path，Cache 和 ShardedCache 共用
+func writeSnapshot(path string, codec SnapshotCodec, snapshot any) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	err = codec.Encode(writer, snapshot)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存快照 %s: %w", path, err)
+	}
+	return nil
+}
+
+// readSnapshot 读取 path 并解码到 snapshot，文件不存在时原样返回 os.Open 的错误
+func readSnapshot(path string, codec SnapshotCodec, snapshot any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := codec.Decode(bufio.NewReader(file), snapshot); err != nil {
+		return fmt.Errorf("读取缓存快照 %s: %w", path, err)
+	}
+	return nil
+}
+
+// snapshotConfig 定期快照的配置
+type snapshotConfig struct {
+	path     string
+	codec    SnapshotCodec
+	interval time.Duration
+	onError  func(error)
+}
+
+// WithPeriodicSnapshot 启动后台 goroutine 每隔 interval 把快照写入 path，Close 时再写最后一次。
+// 写入失败时调用 onError（可以为 nil）。通常与启动时的 LoadSnapshot 配合使用。
+// 用于 NewShardedCache 时由分片缓存把所有分片合并写入一个文件
+func WithPeriodicSnapshot(path string, codec SnapshotCodec, interval time.Duration, onError func(error)) ConfigOption {
+	return func(c *cacheConfig) {
+		c.snapshot = &snapshotConfig{path: path, codec: codec, interval: interval, onError: onError}
+	}
+}
+
+// snapshotLoop 定期写快照，直到 Close 被调用
+func (c *TypedCache[K, V]) snapshotLoop() {
+	defer c.background.Done()
+	c.snapshot.run(c.stop, c.SaveSnapshot)
+}
+
+// run 每隔 interval 调用一次 save，stop 关闭时再调用最后一次后返回
+func (config *snapshotConfig) run(stop <-chan struct{}, save func(path string, codec SnapshotCodec) error) {
+	saveOnce := func() {
+		if err := save(config.path, config.codec); err != nil && config.onError != nil {
+			config.onError(err)
+		}
+	}
+	ticker := time.NewTicker(config.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			saveOnce()
+		case <-stop:
+			saveOnce()
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// stripeCount 分条带计数的条带数
+const stripeCount = 8
+
+// randomStripe 随机选择一个条带。math/rand/v2 的全局函数没有锁，开销只有几纳秒
+func randomStripe() int {
+	return rand.IntN(stripeCount)
+}
+
+// stripedCounter 分条带的计数器。如果所有 goroutine 累加同一个原子变量，它所在的缓存行会在 CPU 核之间来回传递，
+// 核越多越慢；每次累加随机选择一个条带，读取时再汇总，只有读取总数时才需要访问全部条带。
+// 缓存的命中统计和指标注册表的 Counter 都使用它
+type stripedCounter struct {
+	stripes [stripeCount]struct {
+		n atomic.Int64
+		_ [56]byte // 填充到 64 字节，每个条带独占一个缓存行，避免伪共享
+	}
+}
+
+func (s *stripedCounter) Add(n int64) {
+	s.stripes[randomStripe()].n.Add(n)
+}
+
+// Load 汇总所有条带的值
+func (s *stripedCounter) Load() int64 {
+	var total int64
+	for i := range s.stripes {
+		total += s.stripes[i].n.Load()
+	}
+	return total
+}
+
+// Store 把计数设为 n，与并发的 Add 同时调用时结果不确定
+func (s *stripedCounter) Store(n int64) {
+	for i := range s.stripes {
+		s.stripes[i].n.Store(0)
+	}
+	s.stripes[0].n.Store(n)
+}
@@ -1,7 +1,9 @@
 package sync
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 )
@@ -118,6 +120,28 @@ func DemonstrateSafeCounter() {
 	fmt.Printf("最终计数值：%d (期望值: 10000)\n", counter.Value())
 }
 
+// 2.4 进阶：指标注册表，实现见 metrics.go
+// 服务中需要很多个带标签的计数器，并且要被监控系统采集，这时可以用 Registry 代替 SafeCounter
+func DemonstrateMetrics() {
+	registry := NewRegistry()
+	requests := registry.Counter("requests_total", "处理的请求数", "path")
+	latency := registry.Histogram("request_duration_seconds", "请求耗时", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			defer latency.With().ObserveSince(time.Now())
+			requests.With(fmt.Sprintf("/api/%d", id%2)).Inc()
+		}(i)
+	}
+	wg.Wait()
+
+	// 在服务中通常写成 http.Handle("/metrics", registry)
+	registry.WriteTo(os.Stdout)
+}
+
 // 3. RWMutex(读写互斥锁)
 // 3.1 读写锁的基本使用
 // RWMutex 是读写互斥锁，它允许多个读操作同时进行，但写操作需要互斥。
@@ -138,48 +162,7 @@ func DemonstrateRWMutex() {
 	fmt.Printf("最终计算值：%d (期望值: 100)\n", value)
 }
 
-type Cache struct {
-	rwMutext sync.RWMutex
-	data     map[string]interface{}
-	stats    CacheStats
-}
-type CacheStats struct {
-	Hits   int
-	Misses int
-}
-
-func NewCache() *Cache {
-	return &Cache{
-		data: make(map[string]interface{}),
-	}
-}
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.rwMutext.RLock()
-	defer c.rwMutext.RUnlock()
-	value, ok := c.data[key]
-	if ok {
-		c.rwMutext.Lock()
-		c.stats.Hits++
-		c.rwMutext.Unlock()
-		return value, true
-	}
-
-	c.rwMutext.Lock()
-	c.stats.Misses++
-	c.rwMutext.Unlock()
-	return nil, false
-}
-func (c *Cache) Set(key string, value interface{}) {
-	c.rwMutext.Lock()
-	defer c.rwMutext.Unlock()
-	c.data[key] = value
-}
-func (c *Cache) Stats() CacheStats {
-	c.rwMutext.RLock()
-	defer c.rwMutext.RUnlock()
-	return c.stats
-}
-
+// 3.2 实际应用：缓存，实现见 cache.go
 func DemonstrateCache() {
 	cache := NewCache()
 	cache.Set("user:1", "Alice")
@@ -201,6 +184,44 @@ func DemonstrateCache() {
 	fmt.Printf("缓存统计: 命中 %d, 未命中 %d\n", stats.Hits, stats.Misses)
 }
 
+// 3.3 读穿透加载：多个 goroutine 同时读取一个冷键时，GetOrLoad 只会调用一次 loader
+func DemonstrateCacheLoad() {
+	cache := NewCache(WithDefaultTTL(time.Minute))
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		fmt.Printf("从数据库加载 %s\n", key)
+		time.Sleep(100 * time.Millisecond) // 模拟慢查询
+		return "Alice", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrLoad(context.Background(), "user:1", loader)
+		}()
+	}
+	wg.Wait()
+	stats := cache.Stats()
+	fmt.Printf("缓存统计: 命中 %d, 未命中 %d, 加载 %d 次\n", stats.Hits, stats.Misses, stats.Loads)
+}
+
+// 3.4 变更通知：订阅缓存的写入、删除、过期和淘汰事件，订阅者处理慢时丢弃事件而不是阻塞写入
+func DemonstrateCacheEvents() {
+	cache := NewCache(WithMaxEntries(2, EvictLRU))
+	events := cache.Subscribe(16, DropOldest)
+
+	cache.Set("user:1", "Alice")
+	cache.Set("user:2", "Bob")
+	cache.Set("user:3", "Carol") // 淘汰 user:1
+	cache.Delete("user:2")
+	cache.Close() // 关闭缓存时订阅也随之关闭，下面的循环会结束
+
+	for event := range events.C {
+		fmt.Printf("事件: %s %s=%v\n", event.Type, event.Key, event.Value)
+	}
+}
+
 // 4.WaitGroup 等待组
 // 4.1 基本用法
 // WaitGroup 用于等待一组 Goroutinue完成
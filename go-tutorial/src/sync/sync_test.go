@@ -1,6 +1,20 @@
 package sync
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 func TestSync(t *testing.T) {
 	// DemonstrateDataRace()
@@ -11,3 +25,972 @@ func TestSync(t *testing.T) {
 	// DemonstrateSingleton()
 	DemonstrateCond()
 }
+
+// fakeClock 可手动推进的时钟，用于测试过期逻辑
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestCacheTTL(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithDefaultTTL(time.Minute))
+	cache.now = clock.Now
+
+	cache.Set("session", "abc")
+	cache.SetWithTTL("token", "xyz", 10*time.Second)
+	cache.SetWithTTL("config", "v1", 0) // 永不过期
+
+	clock.Advance(30 * time.Second)
+	if _, ok := cache.Get("token"); ok {
+		t.Error("token 已过期, 期望未命中")
+	}
+	if v, ok := cache.Get("session"); !ok || v != "abc" {
+		t.Errorf("Get(session) = %v, %t; 期望 abc", v, ok)
+	}
+
+	clock.Advance(time.Hour)
+	if n := cache.DeleteExpired(); n != 1 {
+		t.Errorf("DeleteExpired() = %d; 期望 1", n)
+	}
+	if v, ok := cache.Get("config"); !ok || v != "v1" {
+		t.Errorf("Get(config) = %v, %t; 期望 v1", v, ok)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Expired != 2 || stats.Evicted != 0 {
+		t.Errorf("Stats() = %+v; 期望 Hits 2, Misses 1, Expired 2", stats)
+	}
+}
+
+func TestCacheEvictionPolicy(t *testing.T) {
+	tests := []struct {
+		policy EvictionPolicy
+		want   []string // 写入 d 之后剩下的键
+	}{
+		{EvictLRU, []string{"a", "c", "d"}},  // b 最久没有访问
+		{EvictLFU, []string{"a", "b", "d"}},  // c 访问次数最少
+		{EvictFIFO, []string{"b", "c", "d"}}, // a 最早写入
+	}
+	for _, tt := range tests {
+		var evicted []string
+		cache := NewCache(WithMaxEntries(3, tt.policy), WithOnEvict(func(key string, _ interface{}, reason EvictionReason) {
+			if reason != ReasonCapacity {
+				t.Errorf("淘汰原因 = %v; 期望 capacity", reason)
+			}
+			evicted = append(evicted, key)
+		}))
+		// LRU 按访问时间排序，每一步推进时钟，使各次访问的时间不同
+		clock := newFakeClock()
+		cache.now = func() time.Time {
+			clock.Advance(time.Millisecond)
+			return clock.Now()
+		}
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		cache.Set("c", 3)
+		cache.Get("b")
+		cache.Get("b")
+		cache.Get("a")
+		cache.Get("a")
+		cache.Get("c")
+		cache.Set("d", 4)
+
+		var keys []string
+		for _, key := range []string{"a", "b", "c", "d"} {
+			if _, ok := cache.Get(key); ok {
+				keys = append(keys, key)
+			}
+		}
+		if !slices.Equal(keys, tt.want) {
+			t.Errorf("策略 %d: 剩余 %v; 期望 %v", tt.policy, keys, tt.want)
+		}
+		if cache.Len() != 3 || cache.Stats().Evicted != 1 || len(evicted) != 1 {
+			t.Errorf("策略 %d: Len() = %d, Stats() = %+v, 回调 %v", tt.policy, cache.Len(), cache.Stats(), evicted)
+		}
+	}
+}
+
+// TestCacheEvictionOrder 条目很多时淘汰顺序仍然是精确的
+func TestCacheEvictionOrder(t *testing.T) {
+	const n = 1000
+	key := func(i int) string { return fmt.Sprintf("key-%d", i) }
+	tests := []struct {
+		policy EvictionPolicy
+		want   []string // 再写入 5 个新键时依次淘汰的键
+	}{
+		{EvictLRU, []string{key(999), key(998), key(997), key(996), key(995)}}, // 按写入的倒序访问
+		{EvictLFU, []string{key(999), key(997), key(995), key(993), key(991)}}, // 奇数键只访问了一次
+		{EvictFIFO, []string{key(0), key(1), key(2), key(3), key(4)}},
+	}
+	for _, tt := range tests {
+		var evicted []string
+		cache := NewCache(WithMaxEntries(n, tt.policy), WithOnEvict(func(key string, _ interface{}, _ EvictionReason) {
+			evicted = append(evicted, key)
+		}))
+		clock := newFakeClock()
+		cache.now = func() time.Time {
+			clock.Advance(time.Millisecond)
+			return clock.Now()
+		}
+		for i := 0; i < n; i++ {
+			cache.Set(key(i), i)
+		}
+		for i := n - 1; i >= 0; i-- {
+			cache.Get(key(i))
+			if i%2 == 0 {
+				cache.Get(key(i))
+			}
+		}
+		for i := 0; i < 5; i++ {
+			newKey := fmt.Sprintf("new-%d", i)
+			cache.Set(newKey, i)
+			// 新键访问次数最多，避免 LFU 下刚写入的键被下一次写入淘汰
+			cache.Get(newKey)
+			cache.Get(newKey)
+			cache.Get(newKey)
+		}
+		if !slices.Equal(evicted, tt.want) {
+			t.Errorf("策略 %d: 淘汰 %v; 期望 %v", tt.policy, evicted, tt.want)
+		}
+		if cache.Len() != n {
+			t.Errorf("策略 %d: Len() = %d; 期望 %d", tt.policy, cache.Len(), n)
+		}
+	}
+
+	// 删除和过期的条目同时从淘汰队列中移除
+	var evicted []string
+	clock := newFakeClock()
+	cache := NewCache(WithMaxEntries(n, EvictFIFO), WithOnEvict(func(key string, _ interface{}, reason EvictionReason) {
+		if reason == ReasonCapacity {
+			evicted = append(evicted, key)
+		}
+	}))
+	cache.now = clock.Now
+	for i := 0; i < n; i++ {
+		cache.SetWithTTL(key(i), i, time.Duration(i%3)*time.Second)
+	}
+	cache.DeleteMany(key(0), key(2))
+	clock.Advance(time.Second)
+	cache.DeleteExpired() // 删除 i%3 == 1 的 333 个键
+	for i := 0; i < 2+333+2; i++ {
+		cache.Set(fmt.Sprintf("new-%d", i), i)
+	}
+	if want := []string{key(3), key(5)}; !slices.Equal(evicted, want) {
+		t.Errorf("淘汰 %v; 期望 %v", evicted, want)
+	}
+	if cache.Len() != n {
+		t.Errorf("Len() = %d; 期望 %d", cache.Len(), n)
+	}
+}
+
+// TestCacheConcurrentGetOrder 读取在加锁之前取时间，晚到的读取不能把访问时间改回更早的值
+func TestCacheConcurrentGetOrder(t *testing.T) {
+	clock := newFakeClock()
+	var stall atomic.Bool
+	entered := make(chan struct{})
+	gate := make(chan struct{})
+	cache := NewCache(WithMaxEntries(2, EvictLRU))
+	cache.now = func() time.Time {
+		now := clock.Now()
+		if stall.CompareAndSwap(true, false) {
+			close(entered)
+			<-gate // 取到时间之后暂停，模拟被调度出去的读取
+		}
+		return now
+	}
+	cache.Set("a", 1)
+	clock.Advance(time.Second)
+	cache.Set("b", 2)
+	clock.Advance(time.Second)
+
+	stall.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Get("a") // 取到的时间早于下面两次读取
+	}()
+	<-entered
+	clock.Advance(time.Second)
+	cache.Get("b")
+	clock.Advance(time.Second)
+	cache.Get("a")
+	close(gate)
+	<-done
+
+	clock.Advance(time.Second)
+	cache.Set("c", 3)
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b 最久没有访问, 期望被淘汰")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("a 最近访问过, 期望保留")
+	}
+}
+
+func TestCacheJanitor(t *testing.T) {
+	expired := make(chan string, 10)
+	cache := NewCache(WithJanitor(10*time.Millisecond), WithOnEvict(func(key string, _ interface{}, reason EvictionReason) {
+		if reason == ReasonExpired {
+			expired <- key
+		}
+	}))
+	for i := 0; i < 3; i++ {
+		cache.SetWithTTL(fmt.Sprintf("key-%d", i), i, 20*time.Millisecond)
+	}
+	cache.Set("keep", true)
+
+	var keys []string
+	for len(keys) < 3 {
+		select {
+		case key := <-expired:
+			keys = append(keys, key)
+		case <-time.After(time.Second):
+			t.Fatalf("后台清理没有删除过期条目, 已删除 %v", keys)
+		}
+	}
+	cache.Close()
+	cache.Close() // 重复调用是安全的
+	if cache.Len() != 1 || cache.Stats().Expired != 3 {
+		t.Errorf("Len() = %d, Stats() = %+v; 期望只剩 keep", cache.Len(), cache.Stats())
+	}
+}
+
+// TestCacheConcurrentStress 多个 goroutine 同时读写、删除、淘汰和过期，需配合 -race 运行
+func TestCacheConcurrentStress(t *testing.T) {
+	cache := NewCache(WithMaxEntries(64, EvictLRU), WithDefaultTTL(5*time.Millisecond), WithJanitor(time.Millisecond))
+	defer cache.Close()
+
+	const goroutines, rounds = 16, 2000
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				key := fmt.Sprintf("key-%d", (g*rounds+i)%100)
+				switch i % 10 {
+				case 0:
+					cache.Set(key, i)
+				case 1:
+					cache.Delete(key)
+				default:
+					cache.Get(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if gets := goroutines * rounds * 8 / 10; stats.Hits+stats.Misses != gets {
+		t.Errorf("Hits + Misses = %d; 期望 %d", stats.Hits+stats.Misses, gets)
+	}
+	if cache.Len() > 64 {
+		t.Errorf("Len() = %d; 不应超过 64", cache.Len())
+	}
+}
+
+// BenchmarkCacheGet 不同并发度下的读取性能：Get 只持有读锁，读取之间互不阻塞，goroutine 增多时单次耗时不应上升。
+// 读取不写共享的变量，在多核机器上用 -cpu 1,4,8 运行时 gets/s 应大致随核数线性增长；单核时各组结果相近
+func BenchmarkCacheGet(b *testing.B) {
+	cache := NewCache()
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		cache.Set(keys[i], i)
+	}
+	for _, parallelism := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("goroutines-%d", parallelism), func(b *testing.B) {
+			b.SetParallelism(parallelism)
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					cache.Get(keys[i%len(keys)])
+					i++
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "gets/s")
+		})
+	}
+}
+
+// BenchmarkCacheGetSet 读写比例 9:1 的混合负载
+func BenchmarkCacheGetSet(b *testing.B) {
+	cache := NewCache(WithMaxEntries(512, EvictLRU))
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%10 == 0 {
+				cache.Set(key, i)
+			} else {
+				cache.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func TestShardedCache(t *testing.T) {
+	cache := NewShardedCache(8, nil, WithMaxEntries(80, EvictLRU))
+	defer cache.Close()
+	if cache.Shards() != 8 {
+		t.Errorf("Shards() = %d; 期望 8", cache.Shards())
+	}
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), i)
+	}
+	if n := cache.Len(); n > 80 || n < 40 {
+		t.Errorf("Len() = %d; 期望不超过总上限 80", n)
+	}
+	if v, ok := cache.Get("key-999"); !ok || v != 999 {
+		t.Errorf("Get(key-999) = %v, %t; 最近写入的键不应被淘汰", v, ok)
+	}
+	cache.Get("missing")
+	if !cache.Delete("key-999") || cache.Delete("key-999") {
+		t.Error("Delete 返回值不正确")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evicted != 1000-cache.Len()-1 {
+		t.Errorf("Stats() = %+v, Len() = %d", stats, cache.Len())
+	}
+
+	// 自定义哈希函数：所有键进入同一个分片
+	single := NewShardedCache(4, func(string) uint64 { return 2 })
+	single.Set("a", 1)
+	single.Set("b", 2)
+	if single.shards[2].Len() != 2 {
+		t.Errorf("自定义哈希函数没有生效, 分片 2 的条目数 = %d", single.shards[2].Len())
+	}
+}
+
+// BenchmarkCacheComparison 对比单锁 Cache、ShardedCache 与 sync.Map 在读写比例 9:1 时的性能
+func BenchmarkCacheComparison(b *testing.B) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	type store interface {
+		Get(key string) (interface{}, bool)
+		Set(key string, value interface{})
+	}
+	run := func(b *testing.B, s store) {
+		for i, key := range keys {
+			s.Set(key, i)
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			i := rand.Intn(len(keys)) // 各 goroutine 从不同位置开始，避免同时访问相同的键
+			for pb.Next() {
+				key := keys[i%len(keys)]
+				if i%10 == 0 {
+					s.Set(key, i)
+				} else {
+					s.Get(key)
+				}
+				i++
+			}
+		})
+	}
+	b.Run("Cache", func(b *testing.B) { run(b, NewCache()) })
+	b.Run("ShardedCache-16", func(b *testing.B) { run(b, NewShardedCache(16, nil)) })
+	b.Run("ShardedCache-64", func(b *testing.B) { run(b, NewShardedCache(64, nil)) })
+	b.Run("sync.Map", func(b *testing.B) { run(b, &syncMapStore{}) })
+}
+
+// syncMapStore 把 sync.Map 适配为与 Cache 相同的接口
+type syncMapStore struct {
+	m sync.Map
+}
+
+func (s *syncMapStore) Get(key string) (interface{}, bool) { return s.m.Load(key) }
+func (s *syncMapStore) Set(key string, value interface{})  { s.m.Store(key, value) }
+
+func TestCacheGetOrLoadCoalescing(t *testing.T) {
+	cache := NewCache()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return "value-of-" + key, nil
+	}
+
+	const goroutines = 100
+	var wg sync.WaitGroup
+	results := make(chan interface{}, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrLoad(context.Background(), "user:1", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad 返回错误: %v", err)
+			}
+			results <- value
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // 等所有 goroutine 进入等待
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader 调用了 %d 次; 期望 1", n)
+	}
+	for value := range results {
+		if value != "value-of-user:1" {
+			t.Errorf("GetOrLoad = %v; 期望 value-of-user:1", value)
+		}
+	}
+	if v, ok := cache.Get("user:1"); !ok || v != "value-of-user:1" {
+		t.Errorf("加载结果没有写入缓存: %v, %t", v, ok)
+	}
+	if stats := cache.Stats(); stats.Loads != 1 {
+		t.Errorf("Stats().Loads = %d; 期望 1", stats.Loads)
+	}
+}
+
+func TestCacheGetOrLoadNegativeCaching(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithErrorTTL(5 * time.Second))
+	cache.now = clock.Now
+
+	errDown := errors.New("数据库不可用")
+	var calls int
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, errDown
+		}
+		return 42, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, errDown) {
+			t.Errorf("第 %d 次 GetOrLoad 错误 = %v; 期望 %v", i+1, err, errDown)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("负缓存有效期内 loader 调用了 %d 次; 期望 1", calls)
+	}
+
+	clock.Advance(6 * time.Second)
+	if v, err := cache.GetOrLoad(context.Background(), "k", loader); err != nil || v != 42 {
+		t.Errorf("负缓存过期后 GetOrLoad = %v, %v; 期望 42", v, err)
+	}
+	if stats := cache.Stats(); stats.Loads != 2 || stats.LoadErrors != 1 {
+		t.Errorf("Stats() = %+v; 期望 Loads 2, LoadErrors 1", stats)
+	}
+
+	// loader panic 转换为错误
+	_, err := cache.GetOrLoad(context.Background(), "panic", func(context.Context, string) (interface{}, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Error("loader panic 时期望返回错误")
+	}
+	if stats := cache.Stats(); stats.LoadErrors != 2 {
+		t.Errorf("Stats().LoadErrors = %d; 期望 panic 也计入加载错误", stats.LoadErrors)
+	}
+}
+
+func TestCacheGetOrLoadPrunesFailures(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(WithErrorTTL(time.Second))
+	cache.now = clock.Now
+
+	errDown := errors.New("数据库不可用")
+	loader := func(context.Context, string) (interface{}, error) {
+		return nil, errDown
+	}
+	// 每一轮的键都不会再被访问，过期的错误应该在之后的写入中被清理，而不是一直留在负缓存里
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 100; i++ {
+			cache.GetOrLoad(context.Background(), fmt.Sprintf("key-%d-%d", round, i), loader)
+		}
+		clock.Advance(2 * time.Second)
+	}
+	cache.loadMu.Lock()
+	size := len(cache.failures)
+	cache.loadMu.Unlock()
+	if size > 2*100 {
+		t.Errorf("负缓存中有 %d 个错误; 期望过期的错误被清理，不超过 200 个", size)
+	}
+}
+
+func TestCacheGetOrLoadContext(t *testing.T) {
+	cache := NewShardedCache(4, nil)
+	release := make(chan struct{})
+	var loaderCtxErr atomic.Value
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		<-release
+		loaderCtxErr.Store(fmt.Sprint(ctx.Err()))
+		return "done", nil
+	}
+
+	// 第一个调用方超时返回，但加载继续进行，另一个等待者拿到结果
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	waiter := make(chan interface{})
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		value, _ := cache.GetOrLoad(context.Background(), "slow", loader)
+		waiter <- value
+	}()
+	if _, err := cache.GetOrLoad(ctx, "slow", loader); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetOrLoad 错误 = %v; 期望 context.DeadlineExceeded", err)
+	}
+	close(release)
+	if value := <-waiter; value != "done" {
+		t.Errorf("其他等待者得到 %v; 期望 done", value)
+	}
+	if got := loaderCtxErr.Load(); got != "<nil>" {
+		t.Errorf("loader 的 ctx 不应随调用方取消, ctx.Err() = %v", got)
+	}
+	if stats := cache.Stats(); stats.Loads != 1 {
+		t.Errorf("Stats().Loads = %d; 期望 1", stats.Loads)
+	}
+}
+
+func TestCacheSnapshotRoundTrip(t *testing.T) {
+	for name, codec := range map[string]SnapshotCodec{"gob": GobCodec{}, "json": JSONCodec{}} {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+			cache := NewCache(WithMaxEntries(10, EvictLFU))
+			cache.now = clock.Now
+			cache.Set("name", "gopher")
+			cache.SetWithTTL("session", "abc", time.Minute)
+			cache.SetWithTTL("token", "xyz", 10*time.Second)
+			cache.Get("name")
+			cache.Get("missing")
+
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			if err := cache.SaveSnapshot(path, codec); err != nil {
+				t.Fatal(err)
+			}
+
+			// 模拟重启：30 秒后在新的缓存中恢复，token 在此期间过期
+			clock.Advance(30 * time.Second)
+			restored := NewCache(WithMaxEntries(10, EvictLFU))
+			restored.now = clock.Now
+			if err := restored.LoadSnapshot(path, codec); err != nil {
+				t.Fatal(err)
+			}
+			if restored.Len() != 2 {
+				t.Errorf("Len() = %d; 期望 2", restored.Len())
+			}
+			if v, ok := restored.Get("name"); !ok || v != "gopher" {
+				t.Errorf("Get(name) = %v, %t; 期望 gopher", v, ok)
+			}
+			if _, ok := restored.Get("token"); ok {
+				t.Error("token 在重启期间已过期, 不应被恢复")
+			}
+			clock.Advance(time.Minute)
+			if _, ok := restored.Get("session"); ok {
+				t.Error("session 应保留原来的过期时间")
+			}
+			if stats := restored.Stats(); stats.Hits != 2 || stats.Misses != 3 {
+				t.Errorf("Stats() = %+v; 期望恢复后的 Hits 2, Misses 3", stats)
+			}
+		})
+	}
+
+	if err := NewCache().LoadSnapshot(filepath.Join(t.TempDir(), "missing"), GobCodec{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadSnapshot(不存在的文件) = %v; 期望 os.ErrNotExist", err)
+	}
+}
+
+// TestCacheRestoreLFU 恢复的访问次数参与 LFU 淘汰
+func TestCacheRestoreLFU(t *testing.T) {
+	clock := newFakeClock()
+	now := func() time.Time {
+		clock.Advance(time.Millisecond)
+		return clock.Now()
+	}
+
+	// 条目数超出上限时淘汰访问次数最少的，即使它是刚恢复的
+	cache := NewCache(WithMaxEntries(2, EvictLFU))
+	cache.now = now
+	cache.Restore(&Snapshot{Entries: []SnapshotEntry{{Key: "a", Value: 1, Hits: 5}, {Key: "b", Value: 2, Hits: 3}, {Key: "c", Value: 3, Hits: 1}}})
+	keys := cache.Keys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("恢复后剩余 %v; 期望 [a b]", keys)
+	}
+
+	// 覆盖已有的键时访问次数变小，淘汰顺序随之改变
+	cache = NewCache(WithMaxEntries(2, EvictLFU))
+	cache.now = now
+	cache.Set("x", 1)
+	cache.Set("y", 2)
+	cache.Get("x")
+	cache.Get("x")
+	cache.Get("x")
+	cache.Get("y")
+	cache.Set("z", 3) // 淘汰 y，x 在淘汰队列中记录的访问次数为 3
+	cache.Restore(&Snapshot{Entries: []SnapshotEntry{{Key: "x", Value: 1, Hits: 0}}})
+	cache.Get("z")
+	cache.Get("z")
+	cache.Set("w", 4)
+	if _, ok := cache.Get("x"); ok {
+		t.Error("x 恢复后访问次数为 0, 期望被淘汰")
+	}
+	if _, ok := cache.Get("z"); !ok {
+		t.Error("z 访问了两次, 期望保留")
+	}
+}
+
+func TestCachePeriodicSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := NewCache(WithPeriodicSnapshot(path, JSONCodec{}, 10*time.Millisecond, func(err error) {
+		t.Errorf("定期快照失败: %v", err)
+	}))
+	cache.Set("a", 1)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("没有写出定期快照")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Close 时写最后一次快照
+	cache.Set("b", 2)
+	cache.Close()
+	restored := NewCache()
+	if err := restored.LoadSnapshot(path, JSONCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := restored.Get("b"); !ok || v != float64(2) {
+		t.Errorf("Get(b) = %v, %t; 期望 Close 前写入的 2（JSON 数字还原为 float64）", v, ok)
+	}
+}
+
+func TestShardedCacheSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sharded.gob")
+	cache := NewShardedCache(8, nil, WithPeriodicSnapshot(path, GobCodec{}, time.Hour, func(err error) {
+		t.Errorf("定期快照失败: %v", err)
+	}))
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), i)
+	}
+	cache.Get("key-1")
+	cache.Close() // 所有分片合并写入一个文件，而不是各分片互相覆盖
+
+	// 分片数不同也能恢复，单锁 Cache 也能读取同一个文件
+	restored := NewShardedCache(3, nil)
+	if err := restored.LoadSnapshot(path, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 100 {
+		t.Errorf("Len() = %d; 期望 100", restored.Len())
+	}
+	for i := 0; i < 100; i++ {
+		if v, ok := restored.Get(fmt.Sprintf("key-%d", i)); !ok || v != i {
+			t.Errorf("Get(key-%d) = %v, %t; 期望 %d", i, v, ok, i)
+		}
+	}
+	if stats := restored.Stats(); stats.Hits != 101 {
+		t.Errorf("Stats().Hits = %d; 期望快照中的 1 加上刚才的 100", stats.Hits)
+	}
+
+	single := NewCache()
+	if err := single.LoadSnapshot(path, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if single.Len() != 100 {
+		t.Errorf("Cache 恢复后 Len() = %d; 期望 100", single.Len())
+	}
+}
+
+func TestTypedCache(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int
+	}
+	var evicted []int
+	cache := NewTypedCache[int, user](WithMaxEntries(3, EvictLRU))
+	// 回调的类型由缓存的键值类型决定，写成 func(string, interface{}, EvictionReason) 时编译报错
+	cache.OnEvict(func(id int, u user, reason EvictionReason) {
+		evicted = append(evicted, id)
+	})
+	cache.SetMany(map[int]user{1: {"alice", 30}, 2: {"bob", 25}, 3: {"carol", 35}})
+	if u, ok := cache.Get(2); !ok || u.Name != "bob" {
+		t.Errorf("Get(2) = %+v, %t; 期望 bob", u, ok)
+	}
+	cache.Get(1)
+	cache.Get(3)
+	cache.Set(4, user{"dave", 40})
+	if !slices.Equal(evicted, []int{2}) {
+		t.Errorf("被淘汰的键 = %v; 期望 [2]", evicted)
+	}
+
+	keys := cache.Keys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []int{1, 3, 4}) {
+		t.Errorf("Keys() = %v; 期望 [1 3 4]", keys)
+	}
+	total := 0
+	for id, u := range cache.All() {
+		total += u.Age
+		cache.Delete(id) // 遍历的是副本，循环体中可以修改缓存
+	}
+	if total != 105 || cache.Len() != 0 {
+		t.Errorf("All() 年龄之和 = %d, 遍历后 Len() = %d; 期望 105 和 0", total, cache.Len())
+	}
+
+	cache.SetMany(map[int]user{5: {"erin", 28}, 6: {"frank", 33}})
+	if got := cache.GetMany(5, 6, 7); len(got) != 2 || got[6].Name != "frank" {
+		t.Errorf("GetMany(5, 6, 7) = %v; 期望 5 和 6 两项", got)
+	}
+	if n := cache.DeleteMany(5, 7); n != 1 || cache.Len() != 1 {
+		t.Errorf("DeleteMany(5, 7) = %d, Len() = %d; 期望 1 和 1", n, cache.Len())
+	}
+
+	// JSON 快照能还原出具体的值类型
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := cache.SaveSnapshot(path, JSONCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewTypedCache[int, user]()
+	if err := restored.LoadSnapshot(path, JSONCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := restored.Get(6); !ok || u != (user{"frank", 33}) {
+		t.Errorf("恢复后 Get(6) = %+v, %t; 期望 frank", u, ok)
+	}
+
+	// 取消回调之后不再调用
+	cache.OnEvict(nil)
+	cache.SetMany(map[int]user{7: {"grace", 41}, 8: {"heidi", 29}, 9: {"ivan", 52}})
+	if !slices.Equal(evicted, []int{2}) {
+		t.Errorf("取消回调后被淘汰的键 = %v; 期望仍为 [2]", evicted)
+	}
+}
+
+func TestCacheEvents(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTypedCache[string, int](WithMaxEntries(2, EvictFIFO))
+	cache.now = clock.Now
+	all := cache.Subscribe(16, DropNewest)
+	removals := cache.Subscribe(16, DropNewest, EventDelete, EventExpire, EventEvict)
+
+	cache.Set("a", 1)
+	cache.Set("a", 2)
+	cache.SetWithTTL("b", 3, time.Second)
+	cache.Set("c", 4) // 淘汰最早写入的 a
+	cache.Delete("c")
+	cache.Delete("missing")
+	clock.Advance(time.Second)
+	cache.Get("b")
+	cache.Close()
+
+	var got []string
+	for event := range all.C {
+		got = append(got, fmt.Sprintf("%s %s=%d", event.Type, event.Key, event.Value))
+	}
+	want := []string{"set a=1", "set a=2", "set b=3", "evict a=2", "set c=4", "delete c=4", "expire b=3"}
+	if !slices.Equal(got, want) {
+		t.Errorf("事件 = %q; 期望 %q", got, want)
+	}
+	got = got[:0]
+	for event := range removals.C {
+		got = append(got, event.Type.String()+" "+event.Key)
+	}
+	if want := []string{"evict a", "delete c", "expire b"}; !slices.Equal(got, want) {
+		t.Errorf("过滤后的事件 = %q; 期望 %q", got, want)
+	}
+	if _, ok := <-cache.Subscribe(1, DropNewest).C; ok {
+		t.Error("缓存关闭后的订阅应立即关闭")
+	}
+
+	// 每种事件类型都有名称，未定义的类型不能被订阅，否则会在位掩码中被静默丢弃
+	for typ := EventType(0); typ < eventTypeCount; typ++ {
+		if typ.String() == "unknown" {
+			t.Errorf("事件类型 %d 没有名称", int(typ))
+		}
+	}
+	for _, typ := range []EventType{-1, eventTypeCount, 8, 64} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Subscribe(%d): 期望 panic", int(typ))
+				}
+			}()
+			cache.Subscribe(1, DropNewest, typ)
+		}()
+	}
+}
+
+func TestCacheSlowSubscriber(t *testing.T) {
+	cache := NewTypedCache[int, int]()
+	newest := cache.Subscribe(2, DropNewest)
+	oldest := cache.Subscribe(2, DropOldest)
+	for i := 0; i < 5; i++ {
+		cache.Set(i, i) // 没有人读取，写入也不能阻塞
+	}
+	newest.Close()
+	oldest.Close()
+	newest.Close()
+
+	for name, tc := range map[string]struct {
+		sub  *Subscription[int, int]
+		want []int
+	}{"DropNewest": {newest, []int{0, 1}}, "DropOldest": {oldest, []int{3, 4}}} {
+		var keys []int
+		for event := range tc.sub.C {
+			keys = append(keys, event.Key)
+		}
+		if !slices.Equal(keys, tc.want) || tc.sub.Dropped() != 3 {
+			t.Errorf("%s: 收到 %v, 丢弃 %d; 期望 %v, 丢弃 3", name, keys, tc.sub.Dropped(), tc.want)
+		}
+	}
+
+	var mu sync.Mutex
+	var sum int
+	var wg sync.WaitGroup
+	wg.Add(3)
+	sub := cache.SubscribeFunc(8, DropNewest, func(event Event[int, int]) {
+		mu.Lock()
+		sum += event.Value
+		mu.Unlock()
+		wg.Done()
+	}, EventSet)
+	defer sub.Close()
+	cache.Set(10, 10)
+	cache.Delete(10)
+	cache.SetMany(map[int]int{20: 20, 30: 30})
+	wg.Wait()
+	if sum != 60 {
+		t.Errorf("SubscribeFunc 收到的值之和 = %d; 期望 60", sum)
+	}
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.Counter("http_requests_total", "处理的请求数", "method", "code")
+	inflight := registry.Gauge("http_inflight_requests", "正在处理的请求数")
+	latency := registry.Histogram("http_request_duration_seconds", "请求耗时\n单位为秒", []float64{0.125, 0.5, 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				requests.With("GET", "200").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+	requests.With("POST", `5"0\0`).Add(2)
+	inflight.With().Set(3)
+	inflight.With().Dec()
+	for _, v := range []float64{0.0625, 0.125, 0.25, 2} { // 二进制下精确的值，总和与累加顺序无关
+		latency.With().Observe(v)
+	}
+
+	if got := requests.With("GET", "200").Value(); got != 5000 {
+		t.Errorf("Counter.Value() = %d; 期望 5000", got)
+	}
+	if registry.Counter("http_requests_total", "", "method", "code").With("GET", "200").Value() != 5000 {
+		t.Error("重复注册同名同标签的指标应返回已有的指标")
+	}
+
+	var buf strings.Builder
+	if _, err := registry.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP http_inflight_requests 正在处理的请求数
+# TYPE http_inflight_requests gauge
+http_inflight_requests 2
+# HELP http_request_duration_seconds 请求耗时\n单位为秒
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.125"} 2
+http_request_duration_seconds_bucket{le="0.5"} 3
+http_request_duration_seconds_bucket{le="1"} 3
+http_request_duration_seconds_bucket{le="+Inf"} 4
+http_request_duration_seconds_sum 2.4375
+http_request_duration_seconds_count 4
+# HELP http_requests_total 处理的请求数
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 5000
+http_requests_total{method="POST",code="5\"0\\0"} 2
+`
+	if buf.String() != want {
+		t.Errorf("WriteTo() =\n%s\n期望\n%s", buf.String(), want)
+	}
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("ServeHTTP 的 Content-Type = %q; 期望 Prometheus 文本格式", ct)
+	}
+	if recorder.Body.String() != want {
+		t.Errorf("ServeHTTP 的响应 =\n%s\n期望与 WriteTo 一致", recorder.Body.String())
+	}
+
+	for name, register := range map[string]func(){
+		"类型冲突": func() { registry.Gauge("http_requests_total", "") },
+		"标签冲突": func() { registry.Counter("http_requests_total", "", "method") },
+		"非法名称": func() { registry.Counter("http-requests", "") },
+		"保留标签": func() { registry.Histogram("size_bytes", "", nil, "le") },
+		"标签个数": func() { requests.With("GET") },
+		"计数减少": func() { requests.With("GET", "200").Add(-1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: 期望 panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func BenchmarkCounter(b *testing.B) {
+	b.Run("SafeCounter", func(b *testing.B) {
+		var counter SafeCounter
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				counter.Increment()
+			}
+		})
+	})
+	b.Run("Counter", func(b *testing.B) {
+		counter := NewRegistry().Counter("ops_total", "").With()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				counter.Inc()
+			}
+		})
+	})
+}